@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/bot"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/parser"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/publisher"
+)
+
+// runFunc runs a command with the arguments left once its flags are parsed
+type runFunc func(ctx context.Context, cfg bot.Config, args []string) error
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	// setup registers the flags of the command and returns what runs it once they are parsed
+	setup func(flags *flag.FlagSet) runFunc
+}
+
+// usageError is returned for invalid arguments, the usage of the command is printed along with it
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// expectArgs checks there are between least and most arguments, most being negative means there is no upper bound
+func expectArgs(args []string, least int, most int) error {
+	if len(args) < least || (most >= 0 && len(args) > most) {
+		return usageError(fmt.Sprintf("unexpected number of arguments: %d", len(args)))
+	}
+	return nil
+}
+
+func optionalArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+var commands = []command{
+	{
+		name:    "run",
+		summary: "runs the bot until it is interrupted, a second interrupt skips draining the post in flight",
+		setup: func(_ *flag.FlagSet) runFunc {
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 0)
+				if err != nil {
+					return err
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				return b.Run(ctx)
+			}
+		},
+	},
+	{
+		name:    "import",
+		args:    "[path]",
+		summary: "imports the excerpts of a file or directory, the configured corpus by default",
+		setup: func(flags *flag.FlagSet) runFunc {
+			quiet := flags.Bool("quiet", false, "do not report progress")
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 1)
+				if err != nil {
+					return err
+				}
+				progress := printProgress
+				if *quiet {
+					progress = nil
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				return b.Import(ctx, optionalArg(args), progress)
+			}
+		},
+	},
+	{
+		name:    "validate",
+		args:    "[path]",
+		summary: "checks the excerpts of a file or directory without connecting to the database",
+		setup: func(_ *flag.FlagSet) runFunc {
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 1)
+				if err != nil {
+					return err
+				}
+				return validate(ctx, cfg.Parser, optionalArg(args))
+			}
+		},
+	},
+	{
+		name:    "post-now",
+		summary: "posts an excerpt right away, the one the schedule would pick by default",
+		setup: func(flags *flag.FlagSet) runFunc {
+			excerptID := flags.String("excerpt-id", "", "ID of the excerpt to post")
+			force := flags.Bool("force", false, "post the excerpt even when it is disabled, removed from the corpus or retracted")
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 0)
+				if err != nil {
+					return err
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				return postNow(ctx, os.Stdout, b.Publisher, *excerptID, *force)
+			}
+		},
+	},
+	{
+		name:    "preview",
+		summary: "prints the tweets an excerpt would be posted as, the one the schedule would pick by default",
+		setup: func(flags *flag.FlagSet) runFunc {
+			excerptID := flags.String("excerpt-id", "", "ID of the excerpt to preview")
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 0)
+				if err != nil {
+					return err
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				excerpt, tweets, err := b.Publisher.Preview(ctx, *excerptID)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("excerpt %s from %s, %s, %s\n", excerpt.ID, excerpt.Series, excerpt.Part, excerpt.Chapter)
+				for i, tweet := range tweets {
+					fmt.Printf("\n--- tweet %d/%d, %d characters\n%s\n", i+1, len(tweets), utf8.RuneCountInString(tweet), tweet)
+				}
+				return nil
+			}
+		},
+	},
+	{
+		name:    "list",
+		summary: "lists the active excerpts in catalogue order",
+		setup: func(flags *flag.FlagSet) runFunc {
+			series := flags.String("series", "", "only list the excerpts of the series, by number or name")
+			part := flags.String("part", "", "only list the excerpts of the part")
+			chapter := flags.String("chapter", "", "only list the excerpts of the chapter")
+			character := flags.String("character", "", "only list the excerpts of the character")
+			tag := flags.String("tag", "", "only list the excerpts with the tag")
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 0)
+				if err != nil {
+					return err
+				}
+				filter := db.ExcerptFilter{Part: *part, Chapter: *chapter, Character: *character}
+				if *series != "" {
+					filter.Series, err = db.ParseSeries(*series)
+					if err != nil {
+						return usageError(err.Error())
+					}
+				}
+				if *tag != "" {
+					filter.Tags = []string{*tag}
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				excerpts, err := b.Excerpts(ctx, filter)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "ID\tPART\tCHAPTER\tEXCERPT")
+				for _, e := range excerpts {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.ID, e.Part, e.Chapter, truncate(e.Excerpt, 60))
+				}
+				return w.Flush()
+			}
+		},
+	},
+	{
+		name:    "history",
+		summary: "lists the latest posts that are not retracted, most recent first",
+		setup: func(flags *flag.FlagSet) runFunc {
+			limit := flags.Int("limit", 20, "maximum number of posts to list")
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 0)
+				if err != nil {
+					return err
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				posts, err := b.History(ctx, *limit)
+				if err != nil {
+					return err
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintln(w, "POSTED ON\tTWEET\tEXCERPT ID\tEXCERPT")
+				for _, p := range posts {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.PostedOn.Format(time.DateTime), p.TweetID, p.Excerpt.ID,
+						truncate(p.Excerpt.Excerpt, 60))
+				}
+				return w.Flush()
+			}
+		},
+	},
+	{
+		name:    "stats",
+		summary: "prints counts of the excerpts and posts",
+		setup: func(_ *flag.FlagSet) runFunc {
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 0)
+				if err != nil {
+					return err
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				stats, err := b.Stats(ctx)
+				if err != nil {
+					return err
+				}
+				lastPostedOn := "never"
+				if !stats.LastPostedOn.IsZero() {
+					lastPostedOn = stats.LastPostedOn.Format(time.DateTime)
+				}
+				w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				fmt.Fprintf(w, "active excerpts\t%d\n", stats.ActiveExcerpts)
+				fmt.Fprintf(w, "inactive excerpts\t%d\n", stats.InactiveExcerpts)
+				fmt.Fprintf(w, "rejected excerpts\t%d\n", stats.RejectedExcerpts)
+				fmt.Fprintf(w, "posts\t%d\n", stats.Posts)
+				fmt.Fprintf(w, "retracted posts\t%d\n", stats.RetractedPosts)
+				fmt.Fprintf(w, "failed posts\t%d\n", stats.FailedPosts)
+				fmt.Fprintf(w, "last posted on\t%s\n", lastPostedOn)
+				return w.Flush()
+			}
+		},
+	},
+	{
+		name:    "migrate",
+		summary: "creates the missing tables and columns and saves the configured catalogue",
+		setup: func(_ *flag.FlagSet) runFunc {
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 0)
+				if err != nil {
+					return err
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				return b.Migrate(ctx)
+			}
+		},
+	},
+	{
+		name:    "auth",
+		summary: "authorizes the bot to post with an OAuth 2.0 user context token",
+		setup: func(_ *flag.FlagSet) runFunc {
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 0, 0)
+				if err != nil {
+					return err
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				return b.Authorize(ctx)
+			}
+		},
+	},
+	{
+		name:    "retract",
+		args:    "<excerpt or tweet id>",
+		summary: "deletes the posted tweets of an excerpt or a single post and records why",
+		setup: func(flags *flag.FlagSet) runFunc {
+			reason := flags.String("reason", "", "why the excerpt is retracted, recorded in the posting history")
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 1, 1)
+				if err != nil {
+					return err
+				}
+				if *reason == "" {
+					return usageError("a reason is required")
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				return b.Retract(ctx, args[0], *reason)
+			}
+		},
+	},
+	{
+		name:    "tag",
+		args:    "<excerpt id> <tag>...",
+		summary: "adds tags to an excerpt",
+		setup: func(_ *flag.FlagSet) runFunc {
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 2, -1)
+				if err != nil {
+					return err
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				return b.Tag(ctx, args[0], args[1:])
+			}
+		},
+	},
+	{
+		name:    "untag",
+		args:    "<excerpt id> <tag>...",
+		summary: "removes tags from an excerpt",
+		setup: func(_ *flag.FlagSet) runFunc {
+			return func(ctx context.Context, cfg bot.Config, args []string) error {
+				err := expectArgs(args, 2, -1)
+				if err != nil {
+					return err
+				}
+				b, err := bot.New(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				return b.Untag(ctx, args[0], args[1:])
+			}
+		},
+	},
+}
+
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func truncate(text string, length int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= length {
+		return text
+	}
+	return string([]rune(text)[:length-1]) + "…"
+}
+
+// postNow posts the excerpt with the given ID, or the next one of the schedule, and prints which one was posted to w.
+// A post Twitter refused is returned as an error so the command fails.
+func postNow(ctx context.Context, w io.Writer, p publisher.Interface, excerptID string, force bool) error {
+	excerpt, err := p.PostNow(ctx, excerptID, force)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "posted excerpt %s\n", excerpt.ID)
+	return nil
+}
+
+// printProgress keeps a single progress line up to date on stderr
+func printProgress(p parser.Progress) {
+	fmt.Fprintf(os.Stderr, "\rparsed %d, saved %d, rejected %d excerpts", p.Parsed, p.Saved, p.Rejected)
+	if p.Done {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// validate prints every problem found in the corpus at path, or the configured one when it is empty, without
+// connecting to the database
+func validate(ctx context.Context, cfg parser.Config, path string) error {
+	err := cfg.Validate()
+	if err != nil {
+		return fmt.Errorf("%w: parser:\n%w", bot.ErrConfigInvalid, err)
+	}
+	if path == "" {
+		path = cfg.CorpusPath
+	}
+	diagnostics, err := parser.Validate(ctx, cfg, path)
+	if err != nil {
+		return err
+	}
+	for _, d := range diagnostics {
+		fmt.Println(d)
+	}
+	if parser.HasErrors(diagnostics) {
+		return fmt.Errorf("%s is invalid", path)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db/dbtest"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/publisher"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter/twittertest"
+	"go.uber.org/zap"
+)
+
+// unreachable configures every required field but points the database at a port nothing listens on
+var unreachable = []string{
+	"-set", "log.level=error",
+	"-set", "psql.host=127.0.0.1", "-set", "psql.port=1", "-set", "psql.user=payne", "-set", "psql.database=payne",
+	"-set", "twitter.consumerKey=consumer-key", "-set", "twitter.consumerSecret=consumer-secret",
+	"-set", "twitter.accessToken=access-token", "-set", "twitter.accessSecret=access-secret",
+}
+
+func TestCommands(t *testing.T) {
+	if len(commands) != 13 {
+		t.Fatalf("expected 13 commands, got %d", len(commands))
+	}
+	for _, cmd := range commands {
+		found, ok := lookup(cmd.name)
+		if !ok || found.summary != cmd.summary || cmd.summary == "" {
+			t.Fatalf("expected %s to be dispatched to with a summary", cmd.name)
+		}
+		if code := run("test", []string{cmd.name, "-h"}); code != exitOK {
+			t.Fatalf("expected the help of %s to exit with %d, got %d", cmd.name, exitOK, code)
+		}
+	}
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	invalid := filepath.Join(t.TempDir(), "excerpts.json")
+	err := os.WriteFile(invalid, []byte(`{"excerpts": [{"series": 1, "part": "Part IV: Mona Sax", "chapter": "?",
+		"excerpt": "They were all dead."}]}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalogue := []string{"-set", "parser.cataloguePath=../../data/catalogue.yaml"}
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{name: "help", args: []string{"help"}, expected: exitOK},
+		{name: "global help", args: []string{"-h"}, expected: exitOK},
+		{name: "invalid override", args: []string{"-set", "psql.user", "stats"}, expected: exitUsage},
+		{name: "run with arguments", args: []string{"run", "now"}, expected: exitUsage},
+		{name: "import of two paths", args: []string{"import", "a.json", "b.json"}, expected: exitUsage},
+		{name: "validate of two paths", args: []string{"validate", "a.json", "b.json"}, expected: exitUsage},
+		{name: "post-now with arguments", args: []string{"post-now", "6d2f1c0a9b8e7d6c"}, expected: exitUsage},
+		{name: "preview with arguments", args: []string{"preview", "6d2f1c0a9b8e7d6c"}, expected: exitUsage},
+		{name: "list of an unknown series", args: []string{"list", "-series", "max payne 4"}, expected: exitUsage},
+		{name: "history with arguments", args: []string{"history", "10"}, expected: exitUsage},
+		{name: "stats with arguments", args: []string{"stats", "all"}, expected: exitUsage},
+		{name: "migrate with arguments", args: []string{"migrate", "up"}, expected: exitUsage},
+		{name: "auth with arguments", args: []string{"auth", "oauth2"}, expected: exitUsage},
+		{name: "retract without id", args: []string{"retract", "-reason", "typo"}, expected: exitUsage},
+		{name: "retract without reason", args: []string{"retract", "6d2f1c0a9b8e7d6c"}, expected: exitUsage},
+		{name: "tag without tags", args: []string{"tag", "6d2f1c0a9b8e7d6c"}, expected: exitUsage},
+		{name: "untag without tags", args: []string{"untag", "6d2f1c0a9b8e7d6c"}, expected: exitUsage},
+		{name: "valid corpus", args: slices.Concat(catalogue, []string{"validate", "../../data/excerpts.json"}), expected: exitOK},
+		{name: "invalid corpus", args: slices.Concat(catalogue, []string{"validate", invalid}), expected: exitFailure},
+		{name: "missing configuration", args: []string{"stats"}, expected: exitConfig},
+		{name: "missing config file", args: []string{"-config", "missing.yaml", "stats"}, expected: exitConfig},
+		{
+			name:     "invalid parser configuration",
+			args:     slices.Concat(catalogue, []string{"-set", "parser.errorPolicy=sometimes", "validate", "../../data/excerpts.json"}),
+			expected: exitConfig,
+		},
+		{name: "unreachable database", args: slices.Concat(unreachable, []string{"stats"}), expected: exitUnavailable},
+		{
+			name:     "forced post on an unreachable database",
+			args:     slices.Concat(unreachable, []string{"post-now", "-force", "-excerpt-id", "6d2f1c0a9b8e7d6c"}),
+			expected: exitUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := run("test", tt.args); code != tt.expected {
+				t.Fatalf("expected exit code %d for %v, got %d", tt.expected, tt.args, code)
+			}
+		})
+	}
+}
+
+func TestPostNow(t *testing.T) {
+	server := twittertest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	logger := zap.NewNop().Sugar()
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{{ID: "4f1c", Excerpt: "Pain and suffering."}}}
+	p := publisher.New(logger, publisher.Config{}, repo, twitter.New(ctx, server.Config(), logger, nil, nil))
+
+	var output bytes.Buffer
+	server.FailNext(twittertest.ErrDuplicate)
+	err := postNow(ctx, &output, p, "4f1c", false)
+	if code := report(io.Discard, "post-now", err); code != exitFailure || output.Len() != 0 {
+		t.Fatalf("expected a refused post to fail with %d, got %d and %q", exitFailure, code, output.String())
+	}
+	err = postNow(ctx, &output, p, "4f1c", false)
+	if code := report(io.Discard, "post-now", err); code != exitOK || output.String() != "posted excerpt 4f1c\n" {
+		t.Fatalf("expected the post to succeed, got %d and %q", code, output.String())
+	}
+}
@@ -2,25 +2,187 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/aaegamysta/listen-2-max-payne/internal/bot"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/parser"
+	"github.com/aaegamysta/listen-2-max-payne/internal/tracing"
 	"github.com/joho/godotenv"
 )
 
+// exit codes of the commands
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+	// exitRejected is returned by imports that completed without the excerpts rejected by the error policy
+	exitRejected = 3
+	exitConfig   = 4
+	// exitUnavailable is returned when the database cannot be connected to, retrying later may succeed
+	exitUnavailable = 5
+)
+
+// flushTimeout is how long exiting waits for the spans left to be exported
+const flushTimeout = 5 * time.Second
+
 func main() {
+	// the .env file is optional since the configuration can come from the environment
 	err := godotenv.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		os.Exit(report(os.Stderr, "loading .env", err))
+	}
+	os.Exit(run(os.Getenv("DEVELOPMENT_ENV"), os.Args[1:]))
+}
+
+// run runs the command named by the first argument left after the global flags, the bot itself when there is none,
+// and returns the exit code
+func run(env string, args []string) int {
+	global := flag.NewFlagSet("listen2maxpayne", flag.ContinueOnError)
+	global.Usage = func() { printUsage(global.Output()) }
+	configPath := global.String("config", "", "path of the config file, ./configs/app.<env>.yaml by default")
+	overrides := overrideFlag{}
+	global.Var(overrides, "set", "overrides a config field by yaml path, like -set publisher.dryRun=true, can be repeated")
+	err := global.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
 	if err != nil {
-		log.Panicf("error loading .env file: %v", err)
-	}
-	env := os.Getenv("DEVELOPMENT_ENV")
-	ctx := context.Background()
-	bot := bot.New(ctx, env)
-	bot.Run(ctx)
-	signals := make(chan os.Signal, 1)
-	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
-	<-signals
+		return exitUsage
+	}
+	args = global.Args()
+	name := "run"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	cmd, ok := lookup(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: listen2maxpayne [global flags] %s %s\n\n%s\n", cmd.name, cmd.args, cmd.summary)
+		flags.PrintDefaults()
+	}
+	runCommand := cmd.setup(flags)
+	err = flags.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		return exitUsage
+	}
+	cfg, err := bot.LoadConfig(bot.ConfigSource{Env: env, Path: *configPath, Overrides: overrides})
+	if err != nil {
+		return report(os.Stderr, "loading the configuration", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// the first signal cancels ctx for a graceful stop, restoring the default handling lets a second one kill the process
+	context.AfterFunc(ctx, stop)
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		return report(os.Stderr, "setting up tracing", fmt.Errorf("%w: tracing: %w", bot.ErrConfigInvalid, err))
+	}
+	defer flushTraces(shutdownTracing)
+	err = runCommand(ctx, cfg, flags.Args())
+	var usage usageError
+	if errors.As(err, &usage) {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		flags.Usage()
+		return exitUsage
+	}
+	return report(os.Stderr, name, err)
+}
+
+// report writes err, returned while doing what, to w and returns the exit code matching it
+func report(w io.Writer, what string, err error) int {
+	var rejected *parser.RejectedError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &rejected):
+		fmt.Fprintf(w, "%s completed with rejections: %v\n", what, err)
+		return exitRejected
+	case errors.Is(err, bot.ErrConfigInvalid):
+		fmt.Fprintf(w, "%s failed: %v\n", what, err)
+		return exitConfig
+	case errors.Is(err, db.ErrDatabaseUnavailable):
+		fmt.Fprintf(w, "%s failed, check the psql configuration and that the database is up: %v\n", what, err)
+		return exitUnavailable
+	default:
+		fmt.Fprintf(w, "%s failed: %v\n", what, err)
+		return exitFailure
+	}
+}
+
+// flushTraces exports the spans left before exiting, giving up after flushTimeout
+func flushTraces(shutdown tracing.ShutdownFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	err := shutdown(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to flush the traces: %v\n", err)
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: listen2maxpayne [global flags] [command] [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "global flags:")
+	fmt.Fprintln(w, "  -config path       path of the config file, ./configs/app.<env>.yaml by default")
+	fmt.Fprintln(w, "  -set field=value   overrides a config field by yaml path, like -set publisher.dryRun=true")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `the bot is run when no command is given, use "listen2maxpayne <command> -h" for the flags of a command`)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "exit codes:")
+	fmt.Fprintln(w, "  0  the command succeeded, or the bot stopped gracefully")
+	fmt.Fprintln(w, "  1  the command failed, or the bot failed to start or to drain in time")
+	fmt.Fprintln(w, "  2  the command or its arguments are invalid")
+	fmt.Fprintln(w, "  3  the import completed without the excerpts rejected by the error policy")
+	fmt.Fprintln(w, "  4  the configuration is invalid")
+	fmt.Fprintln(w, "  5  the database is unavailable")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "config fields can also be set with environment variables named after their yaml path, like %sTWITTER_ACCESS_TOKEN\n", bot.EnvPrefix)
+	fmt.Fprintln(w, "for twitter.accessToken, or read from the file named by the same variable suffixed with _FILE")
+}
+
+// overrideFlag collects the field=value pairs of the repeated -set flag
+type overrideFlag map[string]string
+
+func (o overrideFlag) String() string {
+	pairs := make([]string, 0, len(o))
+	for field, value := range o {
+		pairs = append(pairs, field+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (o overrideFlag) Set(pair string) error {
+	field, value, ok := strings.Cut(pair, "=")
+	if !ok || field == "" {
+		return fmt.Errorf("%q is not a field=value pair", pair)
+	}
+	o[field] = value
+	return nil
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/bot"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/parser"
+)
+
+func TestReport(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{err: nil, expected: exitOK},
+		{err: errors.New("twitter is down"), expected: exitFailure},
+		{err: fmt.Errorf("importing: %w", &parser.RejectedError{}), expected: exitRejected},
+		{err: fmt.Errorf("%w: psql.user is required", bot.ErrConfigInvalid), expected: exitConfig},
+		{err: fmt.Errorf("starting: %w", db.ErrDatabaseUnavailable), expected: exitUnavailable},
+	}
+	for _, tt := range tests {
+		if code := report(io.Discard, "test", tt.err); code != tt.expected {
+			t.Fatalf("expected exit code %d for %v, got %d", tt.expected, tt.err, code)
+		}
+	}
+}
+
+func TestRun_Usage(t *testing.T) {
+	for _, args := range [][]string{{"unknown"}, {"tag", "excerpt-id"}, {"list", "-unknown"}} {
+		if code := run("test", args); code != exitUsage {
+			t.Fatalf("expected exit code %d for %v, got %d", exitUsage, args, code)
+		}
+	}
+}
@@ -5,74 +5,264 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"net"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/admin"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/feed"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/health"
 	"github.com/aaegamysta/listen-2-max-payne/internal/facade/parser"
 	"github.com/aaegamysta/listen-2-max-payne/internal/facade/publisher"
+	"github.com/aaegamysta/listen-2-max-payne/internal/logging"
+	"github.com/aaegamysta/listen-2-max-payne/internal/metrics"
 	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
 	"go.uber.org/zap"
-	"gopkg.in/yaml.v3"
 )
 
 type Bot struct {
-	cfg       Config
-	logger    *zap.SugaredLogger
-	Parser    parser.Interface
-	Publisher publisher.Interface
+	cfg        Config
+	logger     *zap.SugaredLogger
+	repository db.Interface
+	// twitterClient is the dry run destination in dry run mode
+	twitterClient twitter.Interface
+	Parser        parser.Interface
+	Publisher     publisher.Interface
+	Feed          feed.Interface
+	Admin         admin.Interface
+	Health        health.Interface
+	server        *http.Server
 }
 
-func New(ctx context.Context, env string) *Bot {
-	loggerConfig := zap.NewDevelopmentConfig()
-	logger, err := loggerConfig.Build()
-	if err != nil {
-		log.Panicf("something wrong happened while building logger: %v", err)
-	}
-	sugaredLogger := logger.Sugar()
-	configFilePath := fmt.Sprintf("./configs/app.%s.yaml", env)
-	file, err := os.Open(configFilePath)
-	if err != nil {
-		sugaredLogger.Panicf("something wrong happened while opening config file: %v", err)
-	}
-	defer file.Close()
-	fileInBytes, err := io.ReadAll(file)
+// ErrConfigInvalid is wrapped in the errors of configurations that cannot be loaded or are not valid
+var ErrConfigInvalid = errors.New("invalid configuration")
+
+// New validates the whole configuration before creating the clients so every problem is reported at once
+func New(ctx context.Context, cfg Config) (*Bot, error) {
+	err := cfg.Validate()
 	if err != nil {
-		sugaredLogger.Panicf("something wrong happened while reading config file: %v", err)
+		return nil, fmt.Errorf("%w:\n%w", ErrConfigInvalid, err)
 	}
-	var cfg Config
-	err = yaml.Unmarshal(fileInBytes, &cfg)
+	redactor := logging.NewRedactor(cfg.secrets()...)
+	sugaredLogger, err := logging.New(cfg.Log, redactor)
 	if err != nil {
-		sugaredLogger.Panicf("something wrong happened while unmarshalling config file: %v", err)
+		return nil, err
 	}
+	sugaredLogger.Debugw("loaded the configuration", "config", cfg.Redacted())
 	repo := db.New(ctx, cfg.Database, sugaredLogger)
-	err = repo.CreateTablesIfNotExists(ctx)
-	if err != nil {
-		sugaredLogger.Panicf("failed to create tables at the start %w", err)
-	}
 	parser := parser.New(ctx, cfg.Parser, sugaredLogger, repo)
-	twitterClient := twitter.New(ctx, cfg.Twitter, sugaredLogger)
+	twitterClient := twitter.New(ctx, cfg.Twitter, sugaredLogger, redactor, repo)
+	if cfg.Publisher.DryRun {
+		twitterClient, err = twitter.NewDryRun(ctx, cfg.Publisher.DryRunOutput, sugaredLogger, repo)
+		if err != nil {
+			return nil, fmt.Errorf("something wrong happened while creating the dry run destination: %w", err)
+		}
+		sugaredLogger.Warnln("running in dry run mode, nothing will be posted to twitter")
+	}
 	publisher := publisher.New(sugaredLogger, cfg.Publisher, repo, twitterClient)
+	feed := feed.New(ctx, cfg.Feed, sugaredLogger, repo)
+	admin := admin.New(ctx, cfg.Admin, sugaredLogger, redactor, repo, publisher)
+	health := health.New(ctx, cfg.Health, sugaredLogger, redactor, repo, publisher, twitterClient)
 	bot := &Bot{
-		cfg:       cfg,
-		Parser:    parser,
-		Publisher: publisher,
-		logger:    sugaredLogger,
+		cfg:           cfg,
+		repository:    repo,
+		twitterClient: twitterClient,
+		Parser:        parser,
+		Publisher:     publisher,
+		Feed:          feed,
+		Admin:         admin,
+		Health:        health,
+		logger:        sugaredLogger,
 	}
 	bot.logger.Infoln("successfully created the bot...")
-	return bot
+	return bot, nil
+}
+
+// Migrate creates the missing tables and columns and saves the configured catalogue
+func (b *Bot) Migrate(ctx context.Context) error {
+	err := b.repository.CreateTablesIfNotExists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create tables: %w", err)
+	}
+	if b.cfg.Parser.CataloguePath == "" {
+		return nil
+	}
+	catalogue, err := parser.LoadCatalogue(b.cfg.Parser.CataloguePath)
+	if err != nil {
+		return fmt.Errorf("failed to load the catalogue: %w", err)
+	}
+	err = b.repository.SaveCatalogue(ctx, catalogue)
+	if err != nil {
+		return fmt.Errorf("failed to save the catalogue: %w", err)
+	}
+	return nil
 }
 
-func (b *Bot) Run(ctx context.Context) {
+// Run starts the bot and blocks until ctx is done, the components are then stopped in the reverse order they were
+// started in and the post in flight is given up to the drain timeout to complete
+func (b *Bot) Run(ctx context.Context) error {
 	b.logger.Infoln("starting the bot..")
-	f, err := os.Open("./data/excerpts.json")
+	path := b.cfg.Parser.CorpusPath
+	return newLifecycle(b.logger,
+		component{name: "database", start: b.Migrate},
+		component{name: "corpus import", start: b.importCorpus},
+		component{
+			name: "corpus watcher",
+			start: func(ctx context.Context) error {
+				b.Parser.StartWatching(ctx, path)
+				return nil
+			},
+			stop: b.Parser.StopWatching,
+		},
+		component{
+			name: "twitter client",
+			start: func(context.Context) error {
+				return nil
+			},
+			stop: b.closeTwitterClient,
+		},
+		component{
+			name: "publisher",
+			start: func(ctx context.Context) error {
+				b.Publisher.StartPublishingExcerpts(ctx)
+				return nil
+			},
+			stop: b.Publisher.StopPublishingExcerpts,
+		},
+		component{
+			name: "feed generator",
+			start: func(ctx context.Context) error {
+				b.Feed.StartGeneratingFiles(ctx)
+				return nil
+			},
+			stop: b.Feed.StopGeneratingFiles,
+		},
+		component{name: "http server", start: b.startHTTP, stop: b.stopHTTP},
+	).run(ctx, b.cfg.Shutdown.DrainTimeout)
+}
+
+// closeTwitterClient closes the output file of the dry run destination, once nothing is posted anymore
+func (b *Bot) closeTwitterClient(context.Context) error {
+	closer, ok := b.twitterClient.(io.Closer)
+	if !ok {
+		return nil
+	}
+	return closer.Close()
+}
+
+// importCorpus imports the configured corpus, excerpts rejected by the error policy do not prevent the bot from starting
+func (b *Bot) importCorpus(ctx context.Context) error {
+	err := b.Parser.ParseAndSaveExcerptsFromFile(ctx, b.cfg.Parser.CorpusPath, nil)
+	var rejected *parser.RejectedError
+	switch {
+	case errors.As(err, &rejected):
+		b.logger.Warnw("excerpts parsed and saved without the rejected ones", "error", err)
+	case err != nil && !errors.Is(err, io.EOF):
+		return fmt.Errorf("something wrong happened while parsing and saving excerpts: %w", err)
+	default:
+		b.logger.Info("excerpts parsed and saved successfully")
+	}
+	return nil
+}
+
+// Authorize obtains an OAuth 2.0 user context token for the twitter client and stores it in the database
+func (b *Bot) Authorize(ctx context.Context) error {
+	return twitter.Authorize(ctx, b.cfg.Twitter.OAuth2, b.logger, b.repository, os.Stdout)
+}
+
+// Retract deletes the posted tweets of an excerpt or of a single tweet ID and records why
+func (b *Bot) Retract(ctx context.Context, id string, reason string) error {
+	retracted, err := b.Publisher.Retract(ctx, id, reason)
+	if err != nil {
+		if retracted > 0 {
+			b.logger.Warnw("retracted some posts before failing", "id", id, "retracted", retracted)
+		}
+		return err
+	}
+	b.logger.Infow("retracted posts", "id", id, "retracted", retracted)
+	return nil
+}
+
+// Import parses the excerpts of the file or directory at path, the configured corpus when it is empty, and saves
+// them in a single transaction, a *parser.RejectedError is returned when the error policy left excerpts out
+func (b *Bot) Import(ctx context.Context, path string, progress parser.ProgressFunc) error {
+	if path == "" {
+		path = b.cfg.Parser.CorpusPath
+	}
+	err := b.Migrate(ctx)
+	if err != nil {
+		return err
+	}
+	err = b.Parser.ParseAndSaveExcerptsFromFile(ctx, path, progress)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// Excerpts lists the excerpts matching the filter in catalogue order
+func (b *Bot) Excerpts(ctx context.Context, filter db.ExcerptFilter) ([]db.Excerpt, error) {
+	return b.repository.ListExcerpts(ctx, filter)
+}
+
+// History returns the latest posts that are not retracted, most recent first
+func (b *Bot) History(ctx context.Context, limit int) ([]db.PostedExcerpt, error) {
+	return b.repository.GetPostingHistory(ctx, limit)
+}
+
+func (b *Bot) Stats(ctx context.Context) (db.Stats, error) {
+	return b.repository.GetStats(ctx)
+}
+
+// Tag adds the tags to the excerpt with the given ID
+func (b *Bot) Tag(ctx context.Context, excerptID string, tags []string) error {
+	return b.repository.TagExcerpt(ctx, excerptID, tags...)
+}
+
+// Untag removes the tags from the excerpt with the given ID
+func (b *Bot) Untag(ctx context.Context, excerptID string, tags []string) error {
+	return b.repository.UntagExcerpt(ctx, excerptID, tags...)
+}
+
+// startHTTP listens before returning so an address already in use prevents the bot from starting
+func (b *Bot) startHTTP(_ context.Context) error {
+	if b.cfg.HTTP.Address == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", b.cfg.HTTP.Address)
 	if err != nil {
-		b.logger.Panicf("something wrong happened while opening excerpts file: %v", err)
+		return fmt.Errorf("something wrong happened while listening on %s: %w", b.cfg.HTTP.Address, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/feed/", http.StripPrefix("/feed", b.Feed.Handler()))
+	mux.Handle("GET /healthz", b.Health.Handler())
+	mux.Handle("GET /readyz", b.Health.Handler())
+	mux.Handle("GET /metrics", metrics.Handler())
+	if b.cfg.Admin.Token != "" {
+		mux.Handle("/admin/", http.StripPrefix("/admin", b.Admin.Handler()))
+	} else {
+		b.logger.Info("no admin token is configured, the admin API is not served")
 	}
-	err = b.Parser.ParseAndSaveExcerpts(ctx, f)
-	if !errors.Is(err, io.EOF) {
-		b.logger.Panicf("something wrong happened while parsing and saving excerpts: %v", err)
+	b.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		b.logger.Infow("serving http", "address", listener.Addr().String())
+		err := b.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			b.logger.Errorw("something wrong happened while serving http", "error", err)
+		}
+	}()
+	return nil
+}
+
+// stopHTTP stops accepting requests and waits for the ones being served
+func (b *Bot) stopHTTP(ctx context.Context) error {
+	if b.server == nil {
+		return nil
 	}
-	b.logger.Info("excerpts parsed and saved successfully")
-	b.Publisher.StartPublishingExcerpts(ctx)
+	return b.server.Shutdown(ctx)
 }
@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db/dbtest"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/parser"
+	"go.uber.org/zap"
+)
+
+// validConfig is valid but points at a database nothing listens on
+func validConfig(t *testing.T) Config {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+	cfg := defaultConfig()
+	cfg.Database = db.Config{User: "postgres", Host: "127.0.0.1", Port: port, Database: "max_payne"}
+	cfg.Publisher.DryRun = true
+	return cfg
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Database.User = ""
+	_, err := New(context.Background(), cfg)
+	if !errors.Is(err, ErrConfigInvalid) {
+		t.Fatalf("expected %v, got %v", ErrConfigInvalid, err)
+	}
+}
+
+func TestNew_DryRunOutputUnavailable(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Publisher.DryRunOutput = filepath.Join(t.TempDir(), "missing", "tweets.log")
+	_, err := New(context.Background(), cfg)
+	if err == nil || errors.Is(err, ErrConfigInvalid) {
+		t.Fatalf("expected the dry run destination to fail, got %v", err)
+	}
+}
+
+func TestBot_MigrateDatabaseUnavailable(t *testing.T) {
+	cfg := validConfig(t)
+	b, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("expected the bot to be created without connecting, got %v", err)
+	}
+	err = b.Migrate(context.Background())
+	if !errors.Is(err, db.ErrDatabaseUnavailable) {
+		t.Fatalf("expected %v, got %v", db.ErrDatabaseUnavailable, err)
+	}
+	err = b.Run(context.Background())
+	if !errors.Is(err, db.ErrDatabaseUnavailable) {
+		t.Fatalf("expected the bot to fail to start with %v, got %v", db.ErrDatabaseUnavailable, err)
+	}
+}
+
+func TestBot_ImportIntoEmptyDatabase(t *testing.T) {
+	ctx := context.Background()
+	cfg := defaultConfig()
+	// the default paths are relative to the root of the repository
+	cfg.Parser.CorpusPath = filepath.Join("..", "..", cfg.Parser.CorpusPath)
+	cfg.Parser.CataloguePath = filepath.Join("..", "..", cfg.Parser.CataloguePath)
+	logger := zap.NewNop().Sugar()
+	repo := &dbtest.Repository{}
+	b := &Bot{cfg: cfg, logger: logger, repository: repo, Parser: parser.New(ctx, cfg.Parser, logger, repo)}
+	err := b.Migrate(ctx)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	err = b.importCorpus(ctx)
+	if err != nil {
+		t.Fatalf("expected the bundled corpus to be imported, got %v", err)
+	}
+	if len(repo.Excerpts) == 0 || len(repo.Rejected) != 0 {
+		t.Fatalf("expected every excerpt to be imported, got %d imported and %d rejected", len(repo.Excerpts), len(repo.Rejected))
+	}
+	for _, e := range repo.Excerpts {
+		if e.ChapterID == 0 {
+			t.Fatalf("expected the excerpt to be resolved against the catalogue, got %+v", e)
+		}
+	}
+}
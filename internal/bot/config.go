@@ -1,9 +1,18 @@
 package bot
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/admin"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/feed"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/health"
 	"github.com/aaegamysta/listen-2-max-payne/internal/facade/parser"
 	"github.com/aaegamysta/listen-2-max-payne/internal/facade/publisher"
+	"github.com/aaegamysta/listen-2-max-payne/internal/logging"
+	"github.com/aaegamysta/listen-2-max-payne/internal/tracing"
 	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
 )
 
@@ -12,4 +21,81 @@ type Config struct {
 	Twitter   twitter.Config   `yaml:"twitter"`
 	Parser    parser.Config    `yaml:"parser"`
 	Publisher publisher.Config `yaml:"publisher"`
+	Feed      feed.Config      `yaml:"feed"`
+	HTTP      HTTPConfig       `yaml:"http"`
+	Admin     admin.Config     `yaml:"admin"`
+	Health    health.Config    `yaml:"health"`
+	Shutdown  ShutdownConfig   `yaml:"shutdown"`
+	Log       logging.Config   `yaml:"log"`
+	// Tracing is set up by the command line before the bot is created so every command is traced
+	Tracing tracing.Config `yaml:"tracing"`
+}
+
+type HTTPConfig struct {
+	// Address the embedded HTTP server of the feeds, the health checks, the metrics and the admin API listens on, the
+	// server is not started when it is empty
+	Address string `yaml:"address"`
+}
+
+type ShutdownConfig struct {
+	// DrainTimeout is how long stopping waits for the post in flight and the background work before aborting them
+	DrainTimeout time.Duration `yaml:"drainTimeout"`
+}
+
+// Validate reports every missing or invalid field of every section at once, the twitter credentials are not needed
+// in dry run mode
+func (c Config) Validate() error {
+	errs := prefixed("psql", c.Database.Validate())
+	errs = append(errs, prefixed("parser", c.Parser.Validate())...)
+	errs = append(errs, prefixed("publisher", c.Publisher.Validate())...)
+	errs = append(errs, prefixed("feed", c.Feed.Validate())...)
+	errs = append(errs, prefixed("admin", c.Admin.Validate())...)
+	errs = append(errs, prefixed("health", c.Health.Validate())...)
+	errs = append(errs, prefixed("log", c.Log.Validate())...)
+	errs = append(errs, prefixed("tracing", c.Tracing.Validate())...)
+	if c.Shutdown.DrainTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("shutdown.drainTimeout %s must be positive", c.Shutdown.DrainTimeout))
+	}
+	if !c.Publisher.DryRun {
+		errs = append(errs, prefixed("twitter", c.Twitter.Validate())...)
+	}
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the configuration with the secrets that are set redacted, to be logged
+func (c Config) Redacted() Config {
+	for _, field := range configFields(&c) {
+		if field.secret && field.value.String() != "" {
+			field.value.SetString(logging.Redacted)
+		}
+	}
+	return c
+}
+
+// secrets returns the values of the fields tagged secret:"true" that are set, they are redacted from the logs and
+// from the errors served over HTTP
+func (c Config) secrets() []string {
+	var secrets []string
+	for _, field := range configFields(&c) {
+		if field.secret && field.value.String() != "" {
+			secrets = append(secrets, field.value.String())
+		}
+	}
+	return secrets
+}
+
+// prefixed prefixes every error joined in err with the section of the configuration it was found in
+func prefixed(section string, err error) []error {
+	if err == nil {
+		return nil
+	}
+	joined := []error{err}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		joined = j.Unwrap()
+	}
+	errs := make([]error, 0, len(joined))
+	for _, err := range joined {
+		errs = append(errs, fmt.Errorf("%s.%w", section, err))
+	}
+	return errs
 }
@@ -0,0 +1,160 @@
+package bot
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/logging"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "app.yaml")
+	err := os.WriteFile(configPath, []byte(`
+psql:
+  user: postgres
+  password: from-file
+  database: max_payne
+twitter:
+  accessToken: from-file
+  consumerKey: from-file
+parser:
+  watchInterval: 1m
+`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretPath := filepath.Join(dir, "password")
+	err = os.WriteFile(secretPath, []byte("from-secret\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"L2MP_TWITTER_ACCESS_TOKEN":  "from-env",
+		"L2MP_TWITTER_CONSUMER_KEY":  "from-env",
+		"L2MP_PSQL_PASSWORD_FILE":    secretPath,
+		"L2MP_PARSER_WATCH_INTERVAL": "5m",
+		"L2MP_PUBLISHER_FILTER_TAGS": "[winter, dreams]",
+	}
+	cfg, err := LoadConfig(ConfigSource{
+		Path:      configPath,
+		Overrides: map[string]string{"twitter.consumerKey": "from-flag"},
+		LookupEnv: func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected the config to load, got %v", err)
+	}
+	tests := []struct {
+		field    string
+		value    any
+		expected any
+	}{
+		{field: "psql.host", value: cfg.Database.Host, expected: defaultDatabaseHost},
+		{field: "psql.user", value: cfg.Database.User, expected: "postgres"},
+		{field: "psql.password", value: cfg.Database.Password, expected: "from-secret"},
+		{field: "twitter.accessToken", value: cfg.Twitter.AccessToken, expected: "from-env"},
+		{field: "twitter.consumerKey", value: cfg.Twitter.ConsumerKey, expected: "from-flag"},
+		{field: "parser.watchInterval", value: cfg.Parser.WatchInterval, expected: 5 * time.Minute},
+		{field: "publisher.filter.tags", value: strings.Join(cfg.Publisher.Filter.Tags, ","), expected: "winter,dreams"},
+	}
+	for _, tt := range tests {
+		if tt.value != tt.expected {
+			t.Fatalf("expected %s to be %v, got %v", tt.field, tt.expected, tt.value)
+		}
+	}
+}
+
+func TestLoadConfig_InvalidOverrides(t *testing.T) {
+	_, err := LoadConfig(ConfigSource{
+		Path:      filepath.Join(t.TempDir(), "missing.yaml"),
+		LookupEnv: func(string) (string, bool) { return "", false },
+	})
+	if !errors.Is(err, ErrConfigInvalid) {
+		t.Fatalf("expected an explicit config file that is missing to be an invalid configuration, got %v", err)
+	}
+	_, err = LoadConfig(ConfigSource{
+		Env:       "missing",
+		Overrides: map[string]string{"psql.port": "not a port", "twitter.unknown": "x"},
+		LookupEnv: func(key string) (string, bool) { return "x", key == "L2MP_PSQL_USER" || key == "L2MP_PSQL_USER_FILE" },
+	})
+	if err == nil {
+		t.Fatal("expected invalid overrides to fail")
+	}
+	for _, expected := range []string{"L2MP_PSQL_USER and L2MP_PSQL_USER_FILE", "psql.port", `"twitter.unknown"`} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected the error to mention %s, got %v", expected, err)
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Database.Port = 0
+	cfg.Twitter.AccessToken = "token"
+	cfg.Publisher.DialogueStyle = "comic"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected the default config to be invalid")
+	}
+	for _, expected := range []string{
+		"psql.user is required",
+		"psql.database is required",
+		"psql.port 0 is not a valid port",
+		`publisher.dialogueStyle "comic" is not a known style`,
+		"twitter.consumerKey is required with oauth1",
+		"twitter.accessSecret is required with oauth1",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected the error to report %q, got:\n%v", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), "twitter.accessToken") {
+		t.Fatalf("expected the access token to be valid, got:\n%v", err)
+	}
+	cfg.Publisher.DryRun = true
+	if strings.Contains(cfg.Validate().Error(), "twitter.") {
+		t.Fatal("expected twitter credentials not to be required in dry run mode")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for key, expected := range map[string]string{
+		"accessToken":       "ACCESS_TOKEN",
+		"redirectURL":       "REDIRECT_URL",
+		"clientID":          "CLIENT_ID",
+		"tweetURLFormat":    "TWEET_URL_FORMAT",
+		"tweetPeriodPerDay": "TWEET_PERIOD_PER_DAY",
+		"psql":              "PSQL",
+	} {
+		if name := envName(key); name != expected {
+			t.Fatalf("expected %s for %s, got %s", expected, key, name)
+		}
+	}
+}
+
+func TestConfig_Redacted(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Database.Password = "hunter2"
+	cfg.Twitter.AccessSecret = "access-secret"
+	cfg.Twitter.ConsumerKey = "consumer-key"
+	redacted := cfg.Redacted()
+	if redacted.Database.Password != logging.Redacted || redacted.Twitter.AccessSecret != logging.Redacted {
+		t.Fatalf("expected the secrets to be redacted, got %+v", redacted)
+	}
+	if redacted.Twitter.ConsumerKey != "consumer-key" || redacted.Admin.Token != "" {
+		t.Fatalf("expected only the secrets that are set to be redacted, got %+v", redacted)
+	}
+	if cfg.Database.Password != "hunter2" {
+		t.Fatal("expected the configuration redacted not to change")
+	}
+	if secrets := strings.Join(cfg.secrets(), ","); secrets != "hunter2,access-secret" {
+		t.Fatalf("unexpected secrets %s", secrets)
+	}
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// component is a part of the bot that is started in order and stopped in the reverse order
+type component struct {
+	name string
+	// start returns once the component is ready, background work it starts runs until ctx is done
+	start func(ctx context.Context) error
+	// stop waits for the background work of the component to return, giving up when ctx is done, it is optional
+	stop func(ctx context.Context) error
+}
+
+type lifecycle struct {
+	logger     *zap.SugaredLogger
+	components []component
+}
+
+func newLifecycle(logger *zap.SugaredLogger, components ...component) *lifecycle {
+	return &lifecycle{logger: logger, components: components}
+}
+
+// run starts every component and blocks until ctx is done or one fails to start, the components started are then
+// stopped in the reverse order with drainTimeout for all of them to return
+func (l *lifecycle) run(ctx context.Context, drainTimeout time.Duration) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	started, err := l.start(runCtx)
+	if err == nil {
+		<-runCtx.Done()
+		l.logger.Infow("stopping, draining the components", "drain_timeout", drainTimeout)
+	}
+	// the background work of the components only returns once the context they were started with is done
+	cancel()
+	stopCtx, cancelStop := context.WithTimeout(context.WithoutCancel(ctx), drainTimeout)
+	defer cancelStop()
+	return errors.Join(err, l.stop(stopCtx, started))
+}
+
+func (l *lifecycle) start(ctx context.Context) ([]component, error) {
+	started := make([]component, 0, len(l.components))
+	for _, c := range l.components {
+		err := c.start(ctx)
+		if err != nil {
+			return started, fmt.Errorf("something wrong happened while starting the %s: %w", c.name, err)
+		}
+		l.logger.Infow("started component", "component", c.name)
+		started = append(started, c)
+	}
+	return started, nil
+}
+
+func (l *lifecycle) stop(ctx context.Context, started []component) error {
+	var errs []error
+	for index := len(started) - 1; index >= 0; index-- {
+		c := started[index]
+		if c.stop == nil {
+			continue
+		}
+		err := c.stop(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("something wrong happened while stopping the %s: %w", c.name, err))
+			continue
+		}
+		l.logger.Infow("stopped component", "component", c.name)
+	}
+	return errors.Join(errs...)
+}
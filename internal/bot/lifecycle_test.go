@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recorder builds components that record when they are started and stopped
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) component(name string, startErr error, drain time.Duration) component {
+	return component{
+		name: name,
+		start: func(ctx context.Context) error {
+			r.events = append(r.events, "start "+name)
+			return startErr
+		},
+		stop: func(ctx context.Context) error {
+			if drain == 0 {
+				r.events = append(r.events, "stop "+name)
+				return nil
+			}
+			select {
+			case <-time.After(drain):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			r.events = append(r.events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestLifecycle_Run(t *testing.T) {
+	r := &recorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := newLifecycle(zap.NewNop().Sugar(), r.component("database", nil, 0), r.component("publisher", nil, 10*time.Millisecond))
+	time.AfterFunc(10*time.Millisecond, cancel)
+	err := l.run(ctx, time.Second)
+	if err != nil {
+		t.Fatalf("expected a graceful stop, got %v", err)
+	}
+	expected := []string{"start database", "start publisher", "stop publisher", "stop database"}
+	if !reflect.DeepEqual(r.events, expected) {
+		t.Fatalf("expected %v, got %v", expected, r.events)
+	}
+}
+
+func TestLifecycle_RunFailedStart(t *testing.T) {
+	r := &recorder{}
+	failed := errors.New("address already in use")
+	l := newLifecycle(zap.NewNop().Sugar(),
+		r.component("database", nil, 0),
+		r.component("http server", failed, 0),
+		r.component("publisher", nil, 0),
+	)
+	err := l.run(context.Background(), time.Second)
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected the start error, got %v", err)
+	}
+	expected := []string{"start database", "start http server", "stop database"}
+	if !reflect.DeepEqual(r.events, expected) {
+		t.Fatalf("expected %v, got %v", expected, r.events)
+	}
+}
+
+func TestLifecycle_RunDrainTimeout(t *testing.T) {
+	r := &recorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l := newLifecycle(zap.NewNop().Sugar(), r.component("database", nil, 0), r.component("publisher", nil, time.Minute))
+	err := l.run(ctx, 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the drain to time out, got %v", err)
+	}
+	// the components left are still stopped so they get a chance to release what they hold
+	expected := []string{"start database", "start publisher", "stop database"}
+	if !reflect.DeepEqual(r.events, expected) {
+		t.Fatalf("expected %v, got %v", expected, r.events)
+	}
+}
@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes the environment variables overriding the configuration, the rest of their name is the yaml
+// path of the field in upper snake case, like L2MP_TWITTER_ACCESS_TOKEN for twitter.accessToken. The variable
+// suffixed with _FILE names a file to read the value from instead, for secrets mounted as files.
+const EnvPrefix = "L2MP_"
+
+const (
+	defaultCorpusPath           = "./data/excerpts.json"
+	defaultCataloguePath        = "./data/catalogue.yaml"
+	defaultBatchInsertChunkSize = 100
+	defaultDatabaseHost         = "localhost"
+	defaultDatabasePort         = 5432
+	defaultDrainTimeout         = 30 * time.Second
+)
+
+// ConfigSource is where the layers of the configuration are read from, each layer overrides the previous ones:
+// the defaults, the config file, the environment and the overrides given as flags
+type ConfigSource struct {
+	// Env selects ./configs/app.<env>.yaml when Path is empty
+	Env string
+	// Path of the config file, a missing file is only an error when it is given explicitly
+	Path string
+	// Overrides maps yaml paths like twitter.accessToken to their values
+	Overrides map[string]string
+	// LookupEnv reads the environment, os.LookupEnv is used when it is nil
+	LookupEnv func(key string) (string, bool)
+}
+
+func defaultConfig() Config {
+	return Config{
+		Database: db.Config{
+			Host: defaultDatabaseHost,
+			Port: defaultDatabasePort,
+		},
+		Parser: parser.Config{
+			CorpusPath:           defaultCorpusPath,
+			CataloguePath:        defaultCataloguePath,
+			BatchInsertChunkSize: defaultBatchInsertChunkSize,
+		},
+		Shutdown: ShutdownConfig{
+			DrainTimeout: defaultDrainTimeout,
+		},
+	}
+}
+
+// LoadConfig layers the configuration of source, it is not validated so commands only needing a section of it
+// can run without the rest
+func LoadConfig(source ConfigSource) (Config, error) {
+	cfg := defaultConfig()
+	err := loadConfigFile(source, &cfg)
+	if err != nil {
+		return Config{}, err
+	}
+	lookupEnv := source.LookupEnv
+	if lookupEnv == nil {
+		lookupEnv = os.LookupEnv
+	}
+	fields := configFields(&cfg)
+	var errs []error
+	for _, field := range fields {
+		err = field.loadEnv(lookupEnv)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	paths := make([]string, 0, len(source.Overrides))
+	for path := range source.Overrides {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		field, ok := findConfigField(fields, path)
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown config field %q", path))
+			continue
+		}
+		err = field.set(source.Overrides[path])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for %s: %w", path, err))
+		}
+	}
+	if len(errs) > 0 {
+		return Config{}, fmt.Errorf("%w: something wrong happened while applying config overrides: %w", ErrConfigInvalid,
+			errors.Join(errs...))
+	}
+	return cfg, nil
+}
+
+func loadConfigFile(source ConfigSource, cfg *Config) error {
+	path := source.Path
+	if path == "" {
+		path = fmt.Sprintf("./configs/app.%s.yaml", source.Env)
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && source.Path == "" {
+		// the whole configuration can come from the environment, like in containers
+		return nil
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: config file %s does not exist: %w", ErrConfigInvalid, path, err)
+	}
+	if err != nil {
+		return fmt.Errorf("something wrong happened while reading config file: %w", err)
+	}
+	err = yaml.Unmarshal(b, cfg)
+	if err != nil {
+		return fmt.Errorf("%w: something wrong happened while unmarshalling config file %s: %w", ErrConfigInvalid, path, err)
+	}
+	return nil
+}
+
+// configField is a field of the configuration that can be overridden on its own
+type configField struct {
+	path  string
+	env   string
+	value reflect.Value
+	// secret is set by the secret:"true" tag
+	secret bool
+}
+
+// configFields lists the fields of cfg by yaml path, structs are walked into while the other fields, including
+// slices and maps, are overridden as a whole
+func configFields(cfg *Config) []configField {
+	var fields []configField
+	var walk func(path []string, value reflect.Value)
+	walk = func(path []string, value reflect.Value) {
+		for index := range value.NumField() {
+			tag := value.Type().Field(index).Tag
+			name, _, _ := strings.Cut(tag.Get("yaml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			fieldPath := append(path[:len(path):len(path)], name)
+			field := value.Field(index)
+			if field.Kind() == reflect.Struct {
+				walk(fieldPath, field)
+				continue
+			}
+			envNames := make([]string, 0, len(fieldPath))
+			for _, segment := range fieldPath {
+				envNames = append(envNames, envName(segment))
+			}
+			fields = append(fields, configField{
+				path:   strings.Join(fieldPath, "."),
+				env:    EnvPrefix + strings.Join(envNames, "_"),
+				value:  field,
+				secret: tag.Get("secret") == "true",
+			})
+		}
+	}
+	walk(nil, reflect.ValueOf(cfg).Elem())
+	return fields
+}
+
+// findConfigField finds the field of the yaml path, ignoring case
+func findConfigField(fields []configField, path string) (configField, bool) {
+	for _, field := range fields {
+		if strings.EqualFold(field.path, path) {
+			return field, true
+		}
+	}
+	return configField{}, false
+}
+
+// envName turns a camel case yaml key into upper snake case, like redirectURL into REDIRECT_URL
+func envName(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+	for index, r := range runes {
+		if index > 0 && unicode.IsUpper(r) {
+			previous := runes[index-1]
+			nextIsLower := index+1 < len(runes) && unicode.IsLower(runes[index+1])
+			if unicode.IsLower(previous) || unicode.IsDigit(previous) || (unicode.IsUpper(previous) && nextIsLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// loadEnv overrides the field with its environment variable or with the content of the file its _FILE variable
+// names, setting both is an error
+func (f configField) loadEnv(lookupEnv func(string) (string, bool)) error {
+	value, fromEnv := lookupEnv(f.env)
+	path, fromFile := lookupEnv(f.env + "_FILE")
+	switch {
+	case fromEnv && fromFile:
+		return fmt.Errorf("only one of %s and %s_FILE can be set", f.env, f.env)
+	case fromFile:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("something wrong happened while reading %s_FILE: %w", f.env, err)
+		}
+		value = strings.TrimRight(string(b), "\r\n")
+	case !fromEnv:
+		return nil
+	}
+	err := f.set(value)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", f.env, err)
+	}
+	return nil
+}
+
+// set parses value as yaml into the field, strings are taken as is so they need no quoting
+func (f configField) set(value string) error {
+	if f.value.Kind() == reflect.String {
+		f.value.SetString(value)
+		return nil
+	}
+	target := reflect.New(f.value.Type())
+	err := yaml.Unmarshal([]byte(value), target.Interface())
+	if err != nil {
+		return err
+	}
+	f.value.Set(target.Elem())
+	return nil
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Catalogue lists the canonical parts of every series and the chapters of every part, in order, and the characters
+// excerpts can be attributed to
+type Catalogue struct {
+	Series     []CatalogueSeries    `yaml:"series"`
+	Characters []CatalogueCharacter `yaml:"characters"`
+}
+
+type CatalogueSeries struct {
+	Series Series          `yaml:"series"`
+	Parts  []CataloguePart `yaml:"parts"`
+}
+
+type CataloguePart struct {
+	// ID is only known once the catalogue has been saved to the database
+	ID       int                `yaml:"-"`
+	Name     string             `yaml:"name"`
+	Chapters []CatalogueChapter `yaml:"chapters"`
+}
+
+type CatalogueChapter struct {
+	ID   int
+	Name string
+}
+
+// UnmarshalYAML lets chapters be listed by name only
+func (c *CatalogueChapter) UnmarshalYAML(node *yaml.Node) error {
+	return node.Decode(&c.Name)
+}
+
+type CatalogueCharacter struct {
+	ID   int    `yaml:"-"`
+	Name string `yaml:"name"`
+	// Aliases are the other names the character can be referred to by in excerpts, like "Max" for "Max Payne"
+	Aliases []string `yaml:"aliases"`
+}
+
+// UnknownNameError is returned when a part or chapter is not in the catalogue, Suggestion is the closest
+// known name when there is one close enough to likely be a typo
+type UnknownNameError struct {
+	Kind       string
+	Name       string
+	Parent     string
+	Suggestion string
+}
+
+func (e *UnknownNameError) Error() string {
+	msg := fmt.Sprintf("unknown %s %q of %s", e.Kind, e.Name, e.Parent)
+	if e.Suggestion != "" {
+		msg += fmt.Sprintf(", did you mean %q?", e.Suggestion)
+	}
+	return msg
+}
+
+// Resolve finds the chapter of the catalogue matching the names of an excerpt
+func (c Catalogue) Resolve(series Series, part string, chapter string) (CatalogueChapter, error) {
+	if !series.Valid() {
+		return CatalogueChapter{}, fmt.Errorf("unknown series %d", int(series))
+	}
+	var parts []CataloguePart
+	for _, s := range c.Series {
+		if s.Series == series {
+			parts = s.Parts
+		}
+	}
+	partNames := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p.Name != part {
+			partNames = append(partNames, p.Name)
+			continue
+		}
+		chapterNames := make([]string, 0, len(p.Chapters))
+		for _, ch := range p.Chapters {
+			if ch.Name == chapter {
+				return ch, nil
+			}
+			chapterNames = append(chapterNames, ch.Name)
+		}
+		return CatalogueChapter{}, &UnknownNameError{
+			Kind:       "chapter",
+			Name:       chapter,
+			Parent:     fmt.Sprintf("part %q", part),
+			Suggestion: closest(chapter, chapterNames),
+		}
+	}
+	return CatalogueChapter{}, &UnknownNameError{
+		Kind:       "part",
+		Name:       part,
+		Parent:     fmt.Sprintf("series %q", series),
+		Suggestion: closest(part, partNames),
+	}
+}
+
+// ResolveCharacter finds the character of the catalogue with the given name or alias, ignoring case
+func (c Catalogue) ResolveCharacter(name string) (CatalogueCharacter, error) {
+	names := make([]string, 0, len(c.Characters))
+	for _, character := range c.Characters {
+		if strings.EqualFold(character.Name, name) {
+			return character, nil
+		}
+		for _, alias := range character.Aliases {
+			if strings.EqualFold(alias, name) {
+				return character, nil
+			}
+		}
+		names = append(names, character.Name)
+	}
+	return CatalogueCharacter{}, &UnknownNameError{
+		Kind:       "character",
+		Name:       name,
+		Parent:     "the catalogue",
+		Suggestion: closest(name, names),
+	}
+}
+
+// closest returns the candidate with the smallest edit distance to name, ignoring case,
+// as long as the distance is small compared to the length of name
+func closest(name string, candidates []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		distance := levenshtein(strings.ToLower(name), strings.ToLower(candidate))
+		if bestDistance == -1 || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if bestDistance == -1 || bestDistance > max(2, len([]rune(name))/4) {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
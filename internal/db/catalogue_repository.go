@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// legacyExcerptsMigration resolves the chapter of excerpts imported when part and chapter were stored as text,
+// the text columns are dropped once every excerpt is resolved
+const legacyExcerptsMigration = `DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'excerpts' AND column_name = 'chapter') THEN
+		UPDATE excerpts e SET chapter_id = c.id FROM chapters c JOIN parts p ON p.id = c.part_id
+			WHERE e.chapter_id IS NULL AND p.series = e.series AND p.name = e.part AND c.name = e.chapter;
+		IF NOT EXISTS (SELECT 1 FROM excerpts WHERE chapter_id IS NULL) THEN
+			ALTER TABLE excerpts DROP COLUMN part, DROP COLUMN chapter;
+		END IF;
+	END IF;
+END $$;`
+
+func (repository *Impl) SaveCatalogue(ctx context.Context, catalogue Catalogue) error {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("something wrong happened while starting transaction for saving the catalogue: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	for _, series := range catalogue.Series {
+		for partPosition, part := range series.Parts {
+			var partID int
+			err = tx.QueryRow(ctx, `INSERT INTO parts (series, name, position) VALUES ($1, $2, $3)
+				ON CONFLICT (series, name) DO UPDATE SET position = EXCLUDED.position RETURNING id`,
+				series.Series, part.Name, partPosition,
+			).Scan(&partID)
+			if err != nil {
+				return fmt.Errorf("something wrong happened while saving part %q: %w", part.Name, err)
+			}
+			for chapterPosition, chapter := range part.Chapters {
+				_, err = tx.Exec(ctx, `INSERT INTO chapters (part_id, name, position) VALUES ($1, $2, $3)
+					ON CONFLICT (part_id, name) DO UPDATE SET position = EXCLUDED.position`,
+					partID, chapter.Name, chapterPosition,
+				)
+				if err != nil {
+					return fmt.Errorf("something wrong happened while saving chapter %q of part %q: %w", chapter.Name, part.Name, err)
+				}
+			}
+		}
+	}
+	for _, character := range catalogue.Characters {
+		_, err = tx.Exec(ctx, `INSERT INTO characters (name, aliases) VALUES ($1, $2)
+			ON CONFLICT (name) DO UPDATE SET aliases = EXCLUDED.aliases`,
+			character.Name, character.Aliases,
+		)
+		if err != nil {
+			return fmt.Errorf("something wrong happened while saving character %q: %w", character.Name, err)
+		}
+	}
+	_, err = tx.Exec(ctx, legacyExcerptsMigration)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while resolving the chapters of previously imported excerpts: %w", err)
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while committing the catalogue: %w", err)
+	}
+	return nil
+}
+
+func (repository *Impl) GetCatalogue(ctx context.Context) (Catalogue, error) {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return Catalogue{}, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	rows, err := conn.Query(ctx, `SELECT p.series, p.id, p.name, c.id, c.name
+		FROM parts p JOIN chapters c ON c.part_id = p.id
+		ORDER BY p.series, p.position, c.position`)
+	if err != nil {
+		return Catalogue{}, fmt.Errorf("something wrong happened while fetching the catalogue: %w", err)
+	}
+	defer rows.Close()
+	var catalogue Catalogue
+	for rows.Next() {
+		var series Series
+		var part CataloguePart
+		var chapter CatalogueChapter
+		err = rows.Scan(&series, &part.ID, &part.Name, &chapter.ID, &chapter.Name)
+		if err != nil {
+			return Catalogue{}, fmt.Errorf("something wrong happened while scanning the catalogue: %w", err)
+		}
+		if n := len(catalogue.Series); n == 0 || catalogue.Series[n-1].Series != series {
+			catalogue.Series = append(catalogue.Series, CatalogueSeries{Series: series})
+		}
+		s := &catalogue.Series[len(catalogue.Series)-1]
+		if n := len(s.Parts); n == 0 || s.Parts[n-1].ID != part.ID {
+			s.Parts = append(s.Parts, part)
+		}
+		p := &s.Parts[len(s.Parts)-1]
+		p.Chapters = append(p.Chapters, chapter)
+	}
+	if rows.Err() != nil {
+		return Catalogue{}, fmt.Errorf("something wrong happened while reading the catalogue: %w", rows.Err())
+	}
+	rows, err = conn.Query(ctx, `SELECT id, name, COALESCE(aliases, '[]'::jsonb) FROM characters ORDER BY id`)
+	if err != nil {
+		return Catalogue{}, fmt.Errorf("something wrong happened while fetching the characters: %w", err)
+	}
+	catalogue.Characters, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (CatalogueCharacter, error) {
+		var character CatalogueCharacter
+		err := row.Scan(&character.ID, &character.Name, &character.Aliases)
+		return character, err
+	})
+	if err != nil {
+		return Catalogue{}, fmt.Errorf("something wrong happened while scanning the characters: %w", err)
+	}
+	return catalogue, nil
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCatalogue_Resolve(t *testing.T) {
+	catalogue := Catalogue{Series: []CatalogueSeries{{
+		Series: 1,
+		Parts: []CataloguePart{{
+			ID:   1,
+			Name: "Part I: The American Dream",
+			Chapters: []CatalogueChapter{
+				{ID: 1, Name: "The Blood Veins of New York"},
+				{ID: 2, Name: "Let the Gun Do the Talking"},
+			},
+		}},
+	}}}
+	chapter, err := catalogue.Resolve(1, "Part I: The American Dream", "Let the Gun Do the Talking")
+	if err != nil || chapter.ID != 2 {
+		t.Fatalf("expected chapter 2, got %+v: %v", chapter, err)
+	}
+	tests := []struct {
+		part, chapter, suggestion string
+	}{
+		{part: "Part I: The American Dream", chapter: "The Blood Viens of New York", suggestion: "The Blood Veins of New York"},
+		{part: "Part I: The American Dream", chapter: "The the Gun Do the Talking", suggestion: "Let the Gun Do the Talking"},
+		{part: "Part I: The American Dreams", chapter: "Roscoe Street Station", suggestion: "Part I: The American Dream"},
+		{part: "Part I: The American Dream", chapter: "Roscoe Street Station", suggestion: ""},
+	}
+	for _, tt := range tests {
+		_, err := catalogue.Resolve(1, tt.part, tt.chapter)
+		var unknown *UnknownNameError
+		if !errors.As(err, &unknown) || unknown.Suggestion != tt.suggestion {
+			t.Fatalf("expected suggestion %q for %q/%q, got %v", tt.suggestion, tt.part, tt.chapter, err)
+		}
+	}
+}
+
+func TestCatalogue_ResolveCharacter(t *testing.T) {
+	catalogue := Catalogue{Characters: []CatalogueCharacter{
+		{ID: 1, Name: "Max Payne", Aliases: []string{"Max"}},
+		{ID: 2, Name: "Mona Sax", Aliases: []string{"Mona"}},
+	}}
+	for _, name := range []string{"Mona Sax", "mona", "MONA SAX"} {
+		character, err := catalogue.ResolveCharacter(name)
+		if err != nil || character.ID != 2 {
+			t.Fatalf("expected %q to resolve to Mona Sax, got %+v: %v", name, character, err)
+		}
+	}
+	_, err := catalogue.ResolveCharacter("Mona Sux")
+	var unknown *UnknownNameError
+	if !errors.As(err, &unknown) || unknown.Suggestion != "Mona Sax" {
+		t.Fatalf("expected suggestion Mona Sax, got %v", err)
+	}
+}
@@ -1,9 +1,32 @@
 package db
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Config struct {
 	User     string `yaml:"user"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" secret:"true"`
 	Host     string `yaml:"host"`
 	Port     int    `yaml:"port"`
 	Database string `yaml:"database"`
 }
+
+// Validate reports every missing or invalid field at once
+func (c Config) Validate() error {
+	var errs []error
+	if c.User == "" {
+		errs = append(errs, errors.New("user is required"))
+	}
+	if c.Host == "" {
+		errs = append(errs, errors.New("host is required"))
+	}
+	if c.Database == "" {
+		errs = append(errs, errors.New("database is required"))
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port %d is not a valid port", c.Port))
+	}
+	return errors.Join(errs...)
+}
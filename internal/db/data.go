@@ -1,27 +1,80 @@
 package db
 
-type Series int
-
-const (
-	Unspecified Series = iota
-	Original
-	TheFallOfMaxPayne
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
 )
 
-func (s Series) String() string {
-	switch s {
-	case Original:
-		return "1"
-	case TheFallOfMaxPayne:
-		return "The Fall of Max Payne"
-	default:
-		return "0"
+type Excerpt struct {
+	// ID is derived from the excerpt text by NewExcerptID so it is stable across imports, unless the corpus gives one
+	// which also stays the same when the text is edited
+	ID      string `json:"id,omitempty" yaml:"id,omitempty"`
+	Series  Series `json:"series" yaml:"series"`
+	Part    string `json:"part" yaml:"part"`
+	Chapter string `json:"chapter" yaml:"chapter"`
+	Excerpt string `json:"excerpt" yaml:"excerpt"`
+	// Dialogue is the structured form of Excerpt the parser derives from the dialogue markers
+	Dialogue []DialogueLine `json:"dialogue,omitempty" yaml:"dialogue,omitempty"`
+	// Speakers are the characters of the excerpt in the order of the dialogue speaker slots, the first one narrates
+	// excerpts without dialogue
+	Speakers []string `json:"speakers,omitempty" yaml:"speakers,omitempty"`
+	// Tags group excerpts in themes like "noir" or "new york winter", see NormalizeTag
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Source is the corpus file the excerpt was imported from
+	Source string `json:"-" yaml:"-"`
+	// ChapterID references the catalogue chapter Part and Chapter resolve to
+	ChapterID int `json:"-" yaml:"-"`
+	// SpeakerIDs reference the catalogue characters Speakers resolve to
+	SpeakerIDs []int `json:"-" yaml:"-"`
+	// Disabled excerpts are kept in the corpus but never posted on schedule, only operators disable them
+	Disabled bool `json:"-" yaml:"-"`
+	// Removed excerpts are no longer in the corpus, they are only kept for the posting history
+	Removed bool `json:"-" yaml:"-"`
+	// Retracted excerpts had at least one of their posts retracted
+	Retracted bool `json:"-" yaml:"-"`
+}
+
+// Inactive tells whether the excerpt is disabled, removed from the corpus or retracted, such an excerpt is only
+// posted when forced to
+func (e Excerpt) Inactive() bool {
+	return e.Disabled || e.Removed || e.Retracted
+}
+
+// Speaker returns the character speaking the line, or an empty string when the excerpt is not attributed
+func (e Excerpt) Speaker(line DialogueLine) string {
+	slot := line.Speaker
+	if line.Kind == Narration {
+		slot = FirstSpeaker
 	}
+	if slot < 1 || slot > len(e.Speakers) {
+		return ""
+	}
+	return e.Speakers[slot-1]
 }
 
-type Excerpt struct {
-	Series  int    `json:"series"`
-	Part    string `json:"part"`
-	Chapter string `json:"chapter"`
-	Excerpt string `json:"excerpt"`
+// NewExcerptID returns the first 16 hex characters of the SHA-256 of the text, the same value the database
+// backfills for rows inserted before excerpts had an ID
+func NewExcerptID(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+type PostedExcerpt struct {
+	Excerpt Excerpt
+	TweetID string
+	// ThreadTweetIDs are the replies posted after TweetID when the excerpt did not fit in a single tweet
+	ThreadTweetIDs []string
+	PostedOn       time.Time
+}
+
+// FailedPost is an excerpt Twitter refused to post, ExcerptID is empty when the excerpt is no longer in the corpus
+type FailedPost struct {
+	ExcerptID string
+	Excerpt   string
+	Title     string
+	Type      string
+	Detail    string
+	Status    int
+	FailedOn  time.Time
 }
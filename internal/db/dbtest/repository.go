@@ -0,0 +1,322 @@
+// Package dbtest provides an in-memory fake of the database repository for tests.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+	"golang.org/x/oauth2"
+)
+
+// Repository keeps the corpus, the catalogue and the posting history in memory. It only implements what the bot
+// facades use, calling anything else panics on the nil embedded interface.
+//
+// Its fields hold the state of the fake, tests can seed them before using it and inspect them afterwards.
+type Repository struct {
+	db.Interface
+
+	mu sync.Mutex
+	// Catalogue is what SaveCatalogue saved, with the IDs of its parts, chapters and characters assigned.
+	Catalogue db.Catalogue
+	// Excerpts are the excerpts of the corpus in the order they were imported, the removed ones are never selected.
+	Excerpts []db.Excerpt
+	// Filters are the filters excerpts were selected and listed with, in order.
+	Filters []db.ExcerptFilter
+	// Synced are the excerpts of every SyncExcerpts call.
+	Synced [][]db.Excerpt
+	// Import is returned by BeginImport when set, otherwise imports add to Excerpts and Rejected once committed.
+	Import   db.Import
+	Rejected []db.RejectedExcerpt
+	Posts    []db.PostedExcerpt
+	Failed   []twitter.TweetError
+	// Retracted are the IDs of the tweets marked retracted.
+	Retracted []string
+	Token     *oauth2.Token
+	Stats     db.Stats
+	// PingErr is returned by Ping.
+	PingErr error
+	nextID  int
+}
+
+func (r *Repository) Ping(context.Context) error {
+	return r.PingErr
+}
+
+func (r *Repository) CreateTablesIfNotExists(context.Context) error {
+	return nil
+}
+
+func (r *Repository) SaveCatalogue(_ context.Context, catalogue db.Catalogue) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := db.Catalogue{}
+	for _, series := range catalogue.Series {
+		s := db.CatalogueSeries{Series: series.Series}
+		for _, part := range series.Parts {
+			p := db.CataloguePart{ID: r.id(), Name: part.Name}
+			for _, chapter := range part.Chapters {
+				p.Chapters = append(p.Chapters, db.CatalogueChapter{ID: r.id(), Name: chapter.Name})
+			}
+			s.Parts = append(s.Parts, p)
+		}
+		saved.Series = append(saved.Series, s)
+	}
+	for _, character := range catalogue.Characters {
+		character.ID = r.id()
+		saved.Characters = append(saved.Characters, character)
+	}
+	r.Catalogue = saved
+	return nil
+}
+
+func (r *Repository) GetCatalogue(context.Context) (db.Catalogue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Catalogue, nil
+}
+
+func (r *Repository) BeginImport(context.Context) (db.Import, error) {
+	if r.Import != nil {
+		return r.Import, nil
+	}
+	return &memoryImport{repository: r}, nil
+}
+
+// SyncExcerpts makes the excerpts the corpus, keeping whether they are disabled.
+func (r *Repository) SyncExcerpts(_ context.Context, excerpts []db.Excerpt) (db.SyncSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Synced = append(r.Synced, excerpts)
+	previous := make(map[string]db.Excerpt, len(r.Excerpts))
+	for _, e := range r.Excerpts {
+		previous[e.ID] = e
+	}
+	var summary db.SyncSummary
+	synced := make([]db.Excerpt, 0, len(excerpts))
+	for _, e := range excerpts {
+		if e.ID == "" {
+			e.ID = db.NewExcerptID(e.Excerpt)
+		}
+		p, ok := previous[e.ID]
+		switch {
+		case !ok:
+			summary.Added++
+		case !db.Unchanged(p, e):
+			summary.Changed++
+		default:
+			summary.Unchanged++
+		}
+		e.Disabled = p.Disabled
+		delete(previous, e.ID)
+		synced = append(synced, e)
+	}
+	summary.Removed = len(previous)
+	r.Excerpts = synced
+	return summary, nil
+}
+
+// GetRandomExcerpt returns the first excerpt matching the filter, or ErrExcerptNotFound when none does.
+func (r *Repository) GetRandomExcerpt(_ context.Context, filter db.ExcerptFilter) (db.Excerpt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Filters = append(r.Filters, filter)
+	for _, e := range r.Excerpts {
+		if matches(filter, e) {
+			return e, nil
+		}
+	}
+	return db.Excerpt{}, fmt.Errorf("%w: none matches the filter %+v", db.ErrExcerptNotFound, filter)
+}
+
+func (r *Repository) GetExcerpt(_ context.Context, id string) (db.Excerpt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.find(id)
+	if err != nil {
+		return db.Excerpt{}, err
+	}
+	return r.Excerpts[i], nil
+}
+
+func (r *Repository) ListExcerpts(_ context.Context, filter db.ExcerptFilter) ([]db.Excerpt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Filters = append(r.Filters, filter)
+	excerpts := make([]db.Excerpt, 0)
+	for _, e := range r.Excerpts {
+		if matches(filter, e) {
+			excerpts = append(excerpts, e)
+		}
+	}
+	return excerpts, nil
+}
+
+func (r *Repository) SetExcerptDisabled(_ context.Context, excerptID string, disabled bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, err := r.find(excerptID)
+	if err != nil {
+		return err
+	}
+	r.Excerpts[i].Disabled = disabled
+	return nil
+}
+
+func (r *Repository) GetStats(context.Context) (db.Stats, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Stats, nil
+}
+
+func (r *Repository) InsertSuccessfulTweetResponse(_ context.Context,
+	excerpt db.Excerpt,
+	thread []twitter.SucessfullTweetResponse,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	post := db.PostedExcerpt{Excerpt: excerpt, TweetID: thread[0].Data.ID, PostedOn: time.Now()}
+	for _, reply := range thread[1:] {
+		post.ThreadTweetIDs = append(post.ThreadTweetIDs, reply.Data.ID)
+	}
+	r.Posts = append(r.Posts, post)
+	return nil
+}
+
+func (r *Repository) InsertUnsuccessfulTweetResponse(_ context.Context, _ db.Excerpt, res twitter.TweetError) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Failed = append(r.Failed, res)
+	return nil
+}
+
+func (r *Repository) FindPostsToRetract(_ context.Context, id string) ([]db.PostedExcerpt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	posts := make([]db.PostedExcerpt, 0)
+	for _, post := range r.Posts {
+		if slices.Contains(r.Retracted, post.TweetID) {
+			continue
+		}
+		if post.Excerpt.ID == id || post.TweetID == id || slices.Contains(post.ThreadTweetIDs, id) {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nil
+}
+
+func (r *Repository) MarkPostRetracted(_ context.Context, tweetID string, _ string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Retracted = append(r.Retracted, tweetID)
+	for _, post := range r.Posts {
+		if post.TweetID != tweetID {
+			continue
+		}
+		if i, err := r.find(post.Excerpt.ID); err == nil {
+			r.Excerpts[i].Retracted = true
+		}
+	}
+	return nil
+}
+
+func (r *Repository) LoadOAuth2Token(context.Context) (*oauth2.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.Token, nil
+}
+
+func (r *Repository) SaveOAuth2Token(_ context.Context, token *oauth2.Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Token = token
+	return nil
+}
+
+// find returns the index of the excerpt with the given ID, the caller holds the lock
+func (r *Repository) find(id string) (int, error) {
+	i := slices.IndexFunc(r.Excerpts, func(e db.Excerpt) bool {
+		return e.ID == id
+	})
+	if i < 0 {
+		return 0, fmt.Errorf("%w: %s", db.ErrExcerptNotFound, id)
+	}
+	return i, nil
+}
+
+// id returns the next catalogue ID, the caller holds the lock
+func (r *Repository) id() int {
+	r.nextID++
+	return r.nextID
+}
+
+// matches mirrors the SQL conditions of db.ExcerptFilter
+func matches(filter db.ExcerptFilter, e db.Excerpt) bool {
+	switch {
+	case e.Removed,
+		e.Disabled && !filter.IncludeDisabled,
+		filter.Series != db.Unspecified && e.Series != filter.Series,
+		filter.Part != "" && e.Part != filter.Part,
+		filter.Chapter != "" && e.Chapter != filter.Chapter,
+		filter.Query != "" && !strings.Contains(strings.ToLower(e.Excerpt), strings.ToLower(filter.Query)):
+		return false
+	}
+	if filter.Character != "" && !slices.ContainsFunc(e.Speakers, func(speaker string) bool {
+		return strings.EqualFold(speaker, filter.Character)
+	}) {
+		return false
+	}
+	if len(filter.Tags) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(e.Tags, func(tag string) bool {
+		return slices.ContainsFunc(filter.Tags, func(t string) bool {
+			return db.NormalizeTag(t) == db.NormalizeTag(tag)
+		})
+	})
+}
+
+// memoryImport adds the excerpts it inserts to the repository once committed
+type memoryImport struct {
+	repository *Repository
+	excerpts   []db.Excerpt
+	rejected   []db.RejectedExcerpt
+	done       bool
+}
+
+func (i *memoryImport) InsertExcerpts(_ context.Context, excerpts []db.Excerpt) error {
+	i.excerpts = append(i.excerpts, excerpts...)
+	return nil
+}
+
+func (i *memoryImport) RejectExcerpt(_ context.Context, rejected db.RejectedExcerpt) error {
+	i.rejected = append(i.rejected, rejected)
+	return nil
+}
+
+func (i *memoryImport) Commit(context.Context) error {
+	if i.done {
+		return fmt.Errorf("the import is already done")
+	}
+	i.done = true
+	i.repository.mu.Lock()
+	defer i.repository.mu.Unlock()
+	for _, e := range i.excerpts {
+		if e.ID == "" {
+			e.ID = db.NewExcerptID(e.Excerpt)
+		}
+		i.repository.Excerpts = append(i.repository.Excerpts, e)
+	}
+	i.repository.Rejected = append(i.repository.Rejected, i.rejected...)
+	return nil
+}
+
+func (i *memoryImport) Rollback(context.Context) error {
+	i.done = true
+	return nil
+}
@@ -0,0 +1,84 @@
+package db
+
+import "strings"
+
+type LineKind string
+
+const (
+	Narration LineKind = "narration"
+	Speech    LineKind = "speech"
+)
+
+// Speaker slots of the corpus dialogue markers, a "-" line is spoken by the first speaker and a "=" line by the second
+const (
+	NoSpeaker     = 0
+	FirstSpeaker  = 1
+	SecondSpeaker = 2
+)
+
+// DialogueLine is either narration or a line spoken by the speaker in the given slot
+type DialogueLine struct {
+	Kind    LineKind `json:"kind" yaml:"kind"`
+	Speaker int      `json:"speaker,omitempty" yaml:"speaker,omitempty"`
+	Text    string   `json:"text" yaml:"text"`
+}
+
+// DialogueStyle is how the dialogue of an excerpt is written out for a destination
+type DialogueStyle string
+
+const (
+	// DialogueRaw keeps the excerpt as written in the corpus, with its "-" and "=" markers
+	DialogueRaw DialogueStyle = "raw"
+	// DialogueEmDash puts every spoken line on its own line, opened by an em dash
+	DialogueEmDash DialogueStyle = "em-dash"
+	// DialogueQuotes puts every spoken line on its own line, between curly quotes
+	DialogueQuotes DialogueStyle = "quotes"
+	// DialogueLines puts every line on its own line without any mark
+	DialogueLines DialogueStyle = "lines"
+	// DialogueScript opens every spoken line with the name of its speaker, or an em dash when it is not attributed
+	DialogueScript DialogueStyle = "script"
+)
+
+// Render writes out the excerpt in the style, excerpts without structured dialogue and unknown styles are
+// rendered raw
+func (s DialogueStyle) Render(e Excerpt) string {
+	if len(e.Dialogue) == 0 {
+		return e.Excerpt
+	}
+	var format func(line DialogueLine) string
+	switch s {
+	case DialogueEmDash:
+		format = func(line DialogueLine) string { return "— " + line.Text }
+	case DialogueQuotes:
+		format = func(line DialogueLine) string { return "“" + line.Text + "”" }
+	case DialogueLines:
+		format = func(line DialogueLine) string { return line.Text }
+	case DialogueScript:
+		format = func(line DialogueLine) string {
+			if speaker := e.Speaker(line); speaker != "" {
+				return speaker + ": " + line.Text
+			}
+			return "— " + line.Text
+		}
+	default:
+		return e.Excerpt
+	}
+	lines := make([]string, 0, len(e.Dialogue))
+	for _, line := range e.Dialogue {
+		if line.Kind == Speech {
+			lines = append(lines, format(line))
+		} else {
+			lines = append(lines, line.Text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Valid reports whether the style is empty, which keeps the corpus markers, or one of the known styles
+func (s DialogueStyle) Valid() bool {
+	switch s {
+	case "", DialogueRaw, DialogueEmDash, DialogueQuotes, DialogueLines, DialogueScript:
+		return true
+	}
+	return false
+}
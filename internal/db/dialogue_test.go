@@ -0,0 +1,27 @@
+package db
+
+import "testing"
+
+func TestDialogueStyleRender(t *testing.T) {
+	e := Excerpt{
+		Excerpt: "-Come on. Have a cigar. = I don't smoke.",
+		Dialogue: []DialogueLine{
+			{Kind: Speech, Speaker: FirstSpeaker, Text: "Come on. Have a cigar."},
+			{Kind: Speech, Speaker: SecondSpeaker, Text: "I don't smoke."},
+		},
+		Speakers: []string{"Vladimir Lem", "Max Payne"},
+	}
+	tests := map[DialogueStyle]string{
+		"":             e.Excerpt,
+		DialogueRaw:    e.Excerpt,
+		DialogueEmDash: "— Come on. Have a cigar.\n— I don't smoke.",
+		DialogueQuotes: "“Come on. Have a cigar.”\n“I don't smoke.”",
+		DialogueLines:  "Come on. Have a cigar.\nI don't smoke.",
+		DialogueScript: "Vladimir Lem: Come on. Have a cigar.\nMax Payne: I don't smoke.",
+	}
+	for style, expected := range tests {
+		if rendered := style.Render(e); rendered != expected {
+			t.Fatalf("expected %q in the %q style, got %q", expected, style, rendered)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExcerptFilter narrows down the excerpts to pick from, the zero value matches every active excerpt that is not disabled
+type ExcerptFilter struct {
+	Series Series `yaml:"series"`
+	// Part and Chapter only match excerpts of the part and chapter with these names
+	Part    string `yaml:"part"`
+	Chapter string `yaml:"chapter"`
+	// Character only matches excerpts attributed to the character with this name
+	Character string `yaml:"character"`
+	// Tags only matches excerpts with at least one of the tags
+	Tags []string `yaml:"tags"`
+	// Query only matches excerpts containing the text, ignoring case
+	Query string `yaml:"query"`
+	// IncludeDisabled also matches the excerpts disabled by an operator, they are never posted on schedule
+	IncludeDisabled bool `yaml:"-"`
+}
+
+// where returns the SQL condition on the excerpts aliased e, their parts aliased p and chapters aliased c with its
+// arguments, numbered from $1
+func (f ExcerptFilter) where() (string, []any) {
+	conditions := []string{"e.active"}
+	args := make([]any, 0)
+	if !f.IncludeDisabled {
+		conditions = append(conditions, "NOT e.disabled")
+	}
+	if f.Series != Unspecified {
+		args = append(args, f.Series)
+		conditions = append(conditions, fmt.Sprintf("e.series = $%d", len(args)))
+	}
+	if f.Part != "" {
+		args = append(args, f.Part)
+		conditions = append(conditions, fmt.Sprintf("p.name = $%d", len(args)))
+	}
+	if f.Chapter != "" {
+		args = append(args, f.Chapter)
+		conditions = append(conditions, fmt.Sprintf("c.name = $%d", len(args)))
+	}
+	if f.Character != "" {
+		args = append(args, f.Character)
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (SELECT 1 FROM excerpt_speakers s
+			JOIN characters ch ON ch.id = s.character_id WHERE s.excerpt_id = e.id AND lower(ch.name) = lower($%d))`, len(args)))
+	}
+	if len(f.Tags) > 0 {
+		tags := make([]string, 0, len(f.Tags))
+		for _, tag := range f.Tags {
+			tags = append(tags, NormalizeTag(tag))
+		}
+		args = append(args, tags)
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (SELECT 1 FROM excerpt_tags et
+			JOIN tags t ON t.id = et.tag_id WHERE et.excerpt_id = e.id AND t.name = ANY($%d))`, len(args)))
+	}
+	if f.Query != "" {
+		args = append(args, f.Query)
+		conditions = append(conditions, fmt.Sprintf("strpos(lower(e.excerpt), lower($%d)) > 0", len(args)))
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
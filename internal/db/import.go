@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Import is a bulk import of excerpts running in a single transaction, nothing it does is visible before Commit
+type Import interface {
+	InsertExcerpts(ctx context.Context, excerpts []Excerpt) error
+	// RejectExcerpt quarantines an excerpt that could not be imported along with the reason
+	RejectExcerpt(ctx context.Context, rejected RejectedExcerpt) error
+	Commit(ctx context.Context) error
+	// Rollback discards the import, it does nothing once the import is committed
+	Rollback(ctx context.Context) error
+}
+
+type RejectedExcerpt struct {
+	// Source is the corpus file and Position the line and column in it the excerpt was decoded from, when known
+	Source   string
+	Position string
+	// Excerpt holds whatever could be decoded of the excerpt
+	Excerpt Excerpt
+	Error   string
+}
+
+type importTx struct {
+	conn *pgx.Conn
+	tx   pgx.Tx
+}
+
+func (repository *Impl) BeginImport(ctx context.Context) (Import, error) {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		_ = conn.Close(ctx)
+		return nil, fmt.Errorf("something wrong happened while starting transaction for importing excerpts: %w", err)
+	}
+	return &importTx{conn: conn, tx: tx}, nil
+}
+
+func (i *importTx) InsertExcerpts(ctx context.Context, excerpts []Excerpt) error {
+	batch := &pgx.Batch{}
+	for index := range excerpts {
+		err := queueExcerptUpsert(batch, &excerpts[index])
+		if err != nil {
+			return err
+		}
+	}
+	err := i.tx.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return fmt.Errorf("something wrong happened while batch inserting excerpts: %w", err)
+	}
+	return nil
+}
+
+func (i *importTx) RejectExcerpt(ctx context.Context, rejected RejectedExcerpt) error {
+	_, err := i.tx.Exec(ctx, `INSERT INTO rejected_excerpts (rejected_on, source, position, excerpt, error)
+		VALUES ($1, $2, $3, $4, $5)`,
+		time.Now(), rejected.Source, rejected.Position, rejected.Excerpt, rejected.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while quarantining rejected excerpt: %w", err)
+	}
+	return nil
+}
+
+func (i *importTx) Commit(ctx context.Context) error {
+	defer i.conn.Close(ctx)
+	err := i.tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while committing the import: %w", err)
+	}
+	return nil
+}
+
+func (i *importTx) Rollback(ctx context.Context) error {
+	defer i.conn.Close(ctx)
+	err := i.tx.Rollback(ctx)
+	if err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return fmt.Errorf("something wrong happened while rolling back the import: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/metrics"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/aaegamysta/listen-2-max-payne/internal/db")
+
+// queryTracer observes the latency of every query and batch sent on the connections of the repository and traces
+// them along with connecting
+type queryTracer struct{}
+
+type traceKey struct{}
+
+type trace struct {
+	start     time.Time
+	statement string
+}
+
+func (queryTracer) TraceConnectStart(ctx context.Context, _ pgx.TraceConnectStartData) context.Context {
+	ctx, _ = tracer.Start(ctx, "db connect", oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(attribute.String("db.system", "postgresql")))
+	return ctx
+}
+
+func (queryTracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	endSpan(ctx, data.Err)
+}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return startQuery(ctx, statement(data.SQL), attribute.String("db.query.text", data.SQL))
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	observeQuery(ctx, data.Err)
+}
+
+func (queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return startQuery(ctx, "batch", attribute.Int("db.operation.batch.size", data.Batch.Len()))
+}
+
+func (queryTracer) TraceBatchQuery(context.Context, *pgx.Conn, pgx.TraceBatchQueryData) {}
+
+func (queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	observeQuery(ctx, data.Err)
+}
+
+func startQuery(ctx context.Context, statement string, attributes ...attribute.KeyValue) context.Context {
+	ctx, _ = tracer.Start(ctx, "db "+statement, oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(append(attributes,
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", statement),
+		)...))
+	return context.WithValue(ctx, traceKey{}, trace{start: time.Now(), statement: statement})
+}
+
+func observeQuery(ctx context.Context, err error) {
+	endSpan(ctx, err)
+	t, ok := ctx.Value(traceKey{}).(trace)
+	if !ok {
+		return
+	}
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.DBQueryDuration.WithLabelValues(t.statement, outcome).Observe(time.Since(t.start).Seconds())
+}
+
+func endSpan(ctx context.Context, err error) {
+	span := oteltrace.SpanFromContext(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// statement returns the lowercased first keyword of the SQL, keeping the label values few
+func statement(sql string) string {
+	keyword, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	switch keyword {
+	case "select", "insert", "update", "delete", "create", "alter", "with", "begin", "commit", "rollback":
+		return keyword
+	}
+	return "other"
+}
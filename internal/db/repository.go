@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
@@ -10,16 +11,67 @@ import (
 	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
 	"github.com/jackc/pgx/v5"
 	"go.uber.org/zap"
+	"golang.org/x/oauth2"
 )
 
 type Interface interface {
+	// Ping checks the database can be connected to and answers, the error wraps ErrDatabaseUnavailable when not
+	Ping(ctx context.Context) error
 	CreateTablesIfNotExists(ctx context.Context) error
 	BatchInsertExcerpts(ctxc context.Context, excerpts []Excerpt) ([]Excerpt, error)
-	GetRandomExcerpt(ctx context.Context) (Excerpt, error)
-	InsertSuccessfulTweetResponse(ctx context.Context, res twitter.SucessfullTweetResponse) error
+	SyncExcerpts(ctx context.Context, excerpts []Excerpt) (SyncSummary, error)
+	BeginImport(ctx context.Context) (Import, error)
+	// GetRandomExcerpt returns ErrExcerptNotFound when no excerpt matches the filter
+	GetRandomExcerpt(ctx context.Context, filter ExcerptFilter) (Excerpt, error)
+	// GetExcerpt returns ErrExcerptNotFound when there is no excerpt with the given ID, active or not
+	GetExcerpt(ctx context.Context, id string) (Excerpt, error)
+	// ListExcerpts returns the excerpts matching the filter in catalogue order
+	ListExcerpts(ctx context.Context, filter ExcerptFilter) ([]Excerpt, error)
+	GetStats(ctx context.Context) (Stats, error)
+	// InsertSuccessfulTweetResponse records the posting of an excerpt, thread holds the root tweet followed by its replies
+	InsertSuccessfulTweetResponse(ctx context.Context, excerpt Excerpt, thread []twitter.SucessfullTweetResponse) error
 	InsertUnsuccessfulTweetResponse(ctx context.Context, excerpt Excerpt, res twitter.TweetError) error
+	GetPostingHistory(ctx context.Context, limit int) ([]PostedExcerpt, error)
+	// GetFailedPosts returns the latest posts Twitter refused, most recent first
+	GetFailedPosts(ctx context.Context, limit int) ([]FailedPost, error)
+	// FindPostsToRetract returns the posts that are not retracted yet either of the excerpt with the given ID
+	// or the one the given tweet ID belongs to, as its root tweet or one of its replies
+	FindPostsToRetract(ctx context.Context, id string) ([]PostedExcerpt, error)
+	MarkPostRetracted(ctx context.Context, tweetID string, reason string) error
+	InsertDryRunTweet(ctx context.Context, tweet twitter.Tweet, res twitter.SucessfullTweetResponse) error
+	LoadOAuth2Token(ctx context.Context) (*oauth2.Token, error)
+	SaveOAuth2Token(ctx context.Context, token *oauth2.Token) error
+	// SaveCatalogue inserts the parts and chapters of the catalogue that are missing and updates their order
+	SaveCatalogue(ctx context.Context, catalogue Catalogue) error
+	GetCatalogue(ctx context.Context) (Catalogue, error)
+	// TagExcerpt adds the tags to the excerpt with the given ID, tags are normalized by NormalizeTag
+	TagExcerpt(ctx context.Context, excerptID string, tags ...string) error
+	UntagExcerpt(ctx context.Context, excerptID string, tags ...string) error
+	// SetExcerptDisabled disables or enables the excerpt with the given ID, it returns ErrExcerptNotFound when there
+	// is none, disabling outlives imports and syncs of the corpus
+	SetExcerptDisabled(ctx context.Context, excerptID string, disabled bool) error
 }
 
+var (
+	ErrExcerptNotFound = errors.New("excerpt not found")
+	// ErrDatabaseUnavailable is wrapped in the errors of every method when the database cannot be connected to
+	ErrDatabaseUnavailable = errors.New("database unavailable")
+)
+
+// selectSpeakers aggregates the names of the characters of the excerpt aliased e in the order of their slots
+const selectSpeakers = `COALESCE((SELECT jsonb_agg(ch.name ORDER BY s.slot) FROM excerpt_speakers s
+	JOIN characters ch ON ch.id = s.character_id WHERE s.excerpt_id = e.id), '[]'::jsonb)`
+
+// selectTags aggregates the tags of the excerpt aliased e in alphabetical order
+const selectTags = `COALESCE((SELECT jsonb_agg(t.name ORDER BY t.name) FROM excerpt_tags et
+	JOIN tags t ON t.id = et.tag_id WHERE et.excerpt_id = e.id), '[]'::jsonb)`
+
+// selectExcerpts selects excerpts with the names of their part, chapter and speakers resolved from the catalogue
+const selectExcerpts = `SELECT e.id, e.series, p.name, c.name, e.excerpt, e.dialogue, ` + selectSpeakers + `, ` + selectTags + `,
+	COALESCE(e.source, ''), e.chapter_id, e.disabled, NOT e.active,
+	EXISTS (SELECT 1 FROM successful_tweet_response s WHERE s.tweeted_excerpt = e.excerpt AND s.retracted_on IS NOT NULL)
+	FROM excerpts e JOIN chapters c ON c.id = e.chapter_id JOIN parts p ON p.id = c.part_id`
+
 type Impl struct {
 	logger           *zap.SugaredLogger
 	connectionString string
@@ -35,22 +87,114 @@ func New(_ context.Context, cfg Config, logger *zap.SugaredLogger) Interface {
 	return impl
 }
 
+// connect opens a connection to the database, failures wrap ErrDatabaseUnavailable
+func (repository *Impl) connect(ctx context.Context) (*pgx.Conn, error) {
+	cfg, err := pgx.ParseConfig(repository.connectionString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDatabaseUnavailable, err)
+	}
+	cfg.Tracer = queryTracer{}
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDatabaseUnavailable, err)
+	}
+	return conn, nil
+}
+
+func (repository *Impl) Ping(ctx context.Context) error {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	err = conn.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDatabaseUnavailable, err)
+	}
+	return nil
+}
+
 func (repository *Impl) CreateTablesIfNotExists(ctx context.Context) error {
-	conn, err := pgx.Connect(ctx, repository.connectionString)
+	conn, err := repository.connect(ctx)
 	if err != nil {
-		repository.logger.Panicf("something wrong happened while connecting to the database: %v", err)
+		return fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
 	}
+	defer conn.Close(ctx)
 	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		repository.logger.Panicf("something wrong happened while starting transaction for creating tables: %v", err)
+		return fmt.Errorf("something wrong happened while starting transaction for creating tables: %w", err)
+	}
+	// rolling back is a no-op once the transaction is committed
+	defer func() { _ = tx.Rollback(ctx) }()
+	_, err = tx.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS parts (
+			id SERIAL PRIMARY KEY, series INT NOT NULL, name TEXT NOT NULL, position INT NOT NULL,
+			UNIQUE (series, name)
+	);`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while creating parts table: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS chapters (
+			id SERIAL PRIMARY KEY, part_id INT NOT NULL REFERENCES parts(id), name TEXT NOT NULL, position INT NOT NULL,
+			UNIQUE (part_id, name)
+	);`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while creating chapters table: %w", err)
 	}
 	_, err = tx.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS excerpts (
-			series int, part text, chapter text, excerpt text,
+			series int, chapter_id INT REFERENCES chapters(id), excerpt text,
 			PRIMARY KEY (excerpt)
 	);`)
 	if err != nil {
-		repository.logger.Panicf("something wrong happened while creating excerpts table: %v", err)
+		return fmt.Errorf("something wrong happened while creating excerpts table: %w", err)
+	}
+	// excerpts created before the catalogue existed keep their part and chapter text columns until SaveCatalogue
+	// resolves them
+	_, err = tx.Exec(ctx, `
+		ALTER TABLE excerpts ADD COLUMN IF NOT EXISTS chapter_id INT REFERENCES chapters(id);
+		ALTER TABLE excerpts ADD COLUMN IF NOT EXISTS dialogue JSONB;
+		ALTER TABLE excerpts ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT true;
+		ALTER TABLE excerpts ADD COLUMN IF NOT EXISTS source TEXT;`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while adding chapter_id, dialogue, active and source to excerpts table: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		ALTER TABLE excerpts ADD COLUMN IF NOT EXISTS id TEXT UNIQUE;
+		UPDATE excerpts SET id = substring(encode(sha256(convert_to(excerpt, 'UTF8')), 'hex') for 16) WHERE id IS NULL;`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while adding id to excerpts table: %w", err)
+	}
+	// unlike active, which follows the corpus, disabled is only ever changed by operators
+	_, err = tx.Exec(ctx, `ALTER TABLE excerpts ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT false;`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while adding disabled to excerpts table: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS characters (
+			id SERIAL PRIMARY KEY, name TEXT NOT NULL UNIQUE, aliases JSONB
+	);`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while creating characters table: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS excerpt_speakers (
+			excerpt_id TEXT NOT NULL REFERENCES excerpts(id) ON DELETE CASCADE, slot INT NOT NULL,
+			character_id INT NOT NULL REFERENCES characters(id),
+			PRIMARY KEY (excerpt_id, slot)
+	);`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while creating excerpt_speakers table: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS tags (id SERIAL PRIMARY KEY, name TEXT NOT NULL UNIQUE);
+		CREATE TABLE IF NOT EXISTS excerpt_tags (
+			excerpt_id TEXT NOT NULL REFERENCES excerpts(id) ON DELETE CASCADE, tag_id INT NOT NULL REFERENCES tags(id),
+			PRIMARY KEY (excerpt_id, tag_id)
+	);`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while creating tags tables: %w", err)
 	}
 	_, err = tx.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS successful_tweet_response (
@@ -58,7 +202,13 @@ func (repository *Impl) CreateTablesIfNotExists(ctx context.Context) error {
 			FOREIGN KEY (tweeted_excerpt) REFERENCES excerpts(excerpt)
 	);`)
 	if err != nil {
-		repository.logger.Panicf("something wrong happened while creating successful_tweet_response table: %v", err)
+		return fmt.Errorf("something wrong happened while creating successful_tweet_response table: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		ALTER TABLE successful_tweet_response ADD COLUMN IF NOT EXISTS thread_tweet_ids JSONB,
+			ADD COLUMN IF NOT EXISTS retracted_on timestamp, ADD COLUMN IF NOT EXISTS retraction_reason TEXT;`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while adding thread_tweet_ids to successful_tweet_response table: %w", err)
 	}
 	_, err = tx.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS error_tweet_response (
@@ -66,70 +216,205 @@ func (repository *Impl) CreateTablesIfNotExists(ctx context.Context) error {
 			FOREIGN KEY (failed_excerpt) REFERENCES excerpts(excerpt)
 	);`)
 	if err != nil {
-		repository.logger.Panicf("something wrong happened while creating error_tweet_response table: %v", err)
+		return fmt.Errorf("something wrong happened while creating error_tweet_response table: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS rejected_excerpts (
+			id SERIAL PRIMARY KEY, rejected_on timestamptz, source TEXT, position TEXT, excerpt JSONB, error TEXT
+	);`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while creating rejected_excerpts table: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS dry_run_tweet (
+			rendered_on timestamp, tweet_id TEXT PRIMARY KEY, in_reply_to_tweet_id TEXT, text TEXT, media_ids JSONB
+	);`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while creating dry_run_tweet table: %w", err)
+	}
+	// there is only ever one token, the single row is enforced by the check on the id
+	_, err = tx.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS oauth2_token (
+			id INT PRIMARY KEY DEFAULT 1 CHECK (id = 1), access_token TEXT, refresh_token TEXT, token_type TEXT,
+			expiry timestamptz, updated_on timestamptz
+	);`)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while creating oauth2_token table: %w", err)
 	}
 	err = tx.Commit(ctx)
 	if err != nil {
-		repository.logger.Panicf("something wrong happened while committing table creation statements: %v", err)
+		return fmt.Errorf("something wrong happened while committing table creation statements: %w", err)
 	}
 	return nil
 }
 
+// BatchInsertExcerpts inserts the excerpts or updates the ones already imported, the chapter and speakers of
+// every excerpt must have been resolved against the catalogue
 func (repository *Impl) BatchInsertExcerpts(ctx context.Context, excerpts []Excerpt) ([]Excerpt, error) {
-	conn, err := pgx.Connect(ctx, repository.connectionString)
+	conn, err := repository.connect(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
 	}
-	_, err = conn.CopyFrom(ctx,
-		pgx.Identifier{"excerpts"}, []string{"series", "part", "chapter", "excerpt"},
-		pgx.CopyFromSlice(len(excerpts), func(i int) ([]any, error) {
-			if len(excerpts[i].Excerpt) > 280 {
-				repository.logger.Warnf("found an excerpt that will be not be tweetable because it is more than 28 characters %s",
-					excerpts[i].Excerpt,
-				)
-			}
-			return []any{excerpts[i].Series, excerpts[i].Part, excerpts[i].Chapter, excerpts[i].Excerpt}, nil
-		}),
-	)
+	defer conn.Close(ctx)
+	batch := &pgx.Batch{}
+	for i := range excerpts {
+		err = queueExcerptUpsert(batch, &excerpts[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = conn.SendBatch(ctx, batch).Close()
 	if err != nil {
 		return nil, fmt.Errorf("something wrong happened while batch inserting excerpts: %w", err)
 	}
 	return excerpts, nil
 }
 
-func (repository *Impl) GetRandomExcerpt(ctx context.Context) (Excerpt, error) {
-	conn, err := pgx.Connect(ctx, repository.connectionString)
+// queueExcerptUpsert queues the statements inserting or updating the excerpt with its speakers and tags
+func queueExcerptUpsert(batch *pgx.Batch, e *Excerpt) error {
+	if e.ChapterID == 0 {
+		return fmt.Errorf("excerpt %q has not been resolved against the catalogue", e.Excerpt)
+	}
+	if len(e.SpeakerIDs) != len(e.Speakers) {
+		return fmt.Errorf("speakers of excerpt %q have not been resolved against the catalogue", e.Excerpt)
+	}
+	// an excerpt with an explicit ID keeps it when its text is edited, the others are identified by their text
+	conflict, setText := "id", "excerpt = EXCLUDED.excerpt, "
+	if e.ID == "" {
+		e.ID = NewExcerptID(e.Excerpt)
+		conflict, setText = "excerpt", ""
+	}
+	batch.Queue(`INSERT INTO excerpts (id, series, chapter_id, excerpt, dialogue, source) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (`+conflict+`) DO UPDATE SET `+setText+`series = EXCLUDED.series, chapter_id = EXCLUDED.chapter_id,
+			dialogue = EXCLUDED.dialogue, source = EXCLUDED.source, active = true`,
+		e.ID, e.Series, e.ChapterID, e.Excerpt, e.Dialogue, e.Source,
+	)
+	// speakers are replaced as a whole so re-importing an excerpt with fewer speakers drops the stale ones
+	batch.Queue(`DELETE FROM excerpt_speakers WHERE excerpt_id = $1`, e.ID)
+	for slot, characterID := range e.SpeakerIDs {
+		batch.Queue(`INSERT INTO excerpt_speakers (excerpt_id, slot, character_id) VALUES ($1, $2, $3)`,
+			e.ID, slot+1, characterID,
+		)
+	}
+	// imported tags are only ever added so the ones added with TagExcerpt survive re-imports
+	for _, tag := range e.Tags {
+		tag = NormalizeTag(tag)
+		if tag == "" {
+			continue
+		}
+		batch.Queue(`INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, tag)
+		batch.Queue(`INSERT INTO excerpt_tags (excerpt_id, tag_id) SELECT $1, id FROM tags WHERE name = $2
+			ON CONFLICT DO NOTHING`,
+			e.ID, tag,
+		)
+	}
+	return nil
+}
+
+func (repository *Impl) GetRandomExcerpt(ctx context.Context, filter ExcerptFilter) (Excerpt, error) {
+	conn, err := repository.connect(ctx)
 	if err != nil {
 		return Excerpt{}, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
 	}
-	var e Excerpt
-	for tweetableExcerpt := true; tweetableExcerpt; tweetableExcerpt = len(e.Excerpt) > twitter.MaxTweetLength && e.Excerpt == "" {
-		row := conn.QueryRow(ctx, "SELECT * FROM excerpts ORDER BY random()")
-		err = row.Scan(&e.Series, &e.Part, &e.Chapter, &e.Excerpt)
-		if err != nil {
-			return Excerpt{}, fmt.Errorf("something wrong happened while fetching a random excerpt: %w", err)
-		}
+	defer conn.Close(ctx)
+	where, args := filter.where()
+	e, err := scanExcerpt(conn.QueryRow(ctx, selectExcerpts+where+" ORDER BY random() LIMIT 1", args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Excerpt{}, fmt.Errorf("%w: none matches the filter", ErrExcerptNotFound)
+	}
+	if err != nil {
+		return Excerpt{}, fmt.Errorf("something wrong happened while fetching a random excerpt: %w", err)
 	}
-	repository.logger.Infof("successfully fetched excerpt %s from chapter %s, part %s", e.Excerpt, e.Chapter, e.Part)
+	repository.logger.Debugw("fetched a random excerpt", "excerpt_id", e.ID, "chapter", e.Chapter, "part", e.Part, "source", e.Source)
 	return e, nil
 }
 
-func (repository *Impl) InsertSuccessfulTweetResponse(ctx context.Context, res twitter.SucessfullTweetResponse) error {
-	conn, err := pgx.Connect(ctx, repository.connectionString)
+func (repository *Impl) GetExcerpt(ctx context.Context, id string) (Excerpt, error) {
+	conn, err := repository.connect(ctx)
 	if err != nil {
-		return fmt.Errorf(`something wrong happened while acquiring connection to 
-		the database while trying to insert successful tweet response: %w`,
-			err)
+		return Excerpt{}, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	e, err := scanExcerpt(conn.QueryRow(ctx, selectExcerpts+" WHERE e.id = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Excerpt{}, fmt.Errorf("%w: %s", ErrExcerptNotFound, id)
+	}
+	if err != nil {
+		return Excerpt{}, fmt.Errorf("something wrong happened while fetching excerpt %s: %w", id, err)
+	}
+	return e, nil
+}
+
+func (repository *Impl) ListExcerpts(ctx context.Context, filter ExcerptFilter) ([]Excerpt, error) {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	where, args := filter.where()
+	rows, err := conn.Query(ctx, selectExcerpts+where+" ORDER BY e.series, p.position, c.position, e.excerpt", args...)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while listing excerpts: %w", err)
+	}
+	excerpts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Excerpt, error) {
+		return scanExcerpt(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while scanning excerpts: %w", err)
+	}
+	return excerpts, nil
+}
+
+func (repository *Impl) SetExcerptDisabled(ctx context.Context, excerptID string, disabled bool) error {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	tag, err := conn.Exec(ctx, `UPDATE excerpts SET disabled = $2 WHERE id = $1`, excerptID, disabled)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while updating excerpt %s: %w", excerptID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ErrExcerptNotFound, excerptID)
+	}
+	return nil
+}
+
+// scanExcerpt scans a row selected by selectExcerpts
+func scanExcerpt(row pgx.Row) (Excerpt, error) {
+	var e Excerpt
+	err := row.Scan(&e.ID, &e.Series, &e.Part, &e.Chapter, &e.Excerpt, &e.Dialogue, &e.Speakers, &e.Tags, &e.Source, &e.ChapterID,
+		&e.Disabled, &e.Removed, &e.Retracted)
+	return e, err
+}
+
+func (repository *Impl) InsertSuccessfulTweetResponse(ctx context.Context,
+	excerpt Excerpt,
+	thread []twitter.SucessfullTweetResponse,
+) error {
+	if len(thread) == 0 {
+		return errors.New("cannot insert successful tweet response for an empty thread")
+	}
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while acquiring connection to the database while trying to insert successful tweet response: %w", err)
+	}
+	defer conn.Close(ctx)
+	root := thread[0]
+	replyIDs := make([]string, 0, len(thread)-1)
+	for _, reply := range thread[1:] {
+		replyIDs = append(replyIDs, reply.Data.ID)
 	}
 	_, err = conn.Exec(ctx, `INSERT INTO 
-		successful_tweet_response (posted_on, tweeted_excerpt, tweet_id, edit_history_tweet_ids) 
-		VALUES ($1, $2, $3, $4)`,
-		time.Now(), res.Data.Text, res.Data.ID, res.Data.EditHistoryTweetIDs,
+		successful_tweet_response (posted_on, tweeted_excerpt, tweet_id, edit_history_tweet_ids, thread_tweet_ids) 
+		VALUES ($1, $2, $3, $4, $5)`,
+		time.Now(), excerpt.Excerpt, root.Data.ID, root.Data.EditHistoryTweetIDs, replyIDs,
 	)
 	if err != nil {
 		return fmt.Errorf("something wrong happened while inserting successful tweet response: %w", err)
 	}
-	repository.logger.Infof("inserted the successful tweet response for excerpt %s", res.Data.Text)
+	repository.logger.Debugw("recorded the post", "excerpt_id", excerpt.ID, "tweet_id", root.Data.ID)
 	return nil
 }
 
@@ -137,12 +422,11 @@ func (repository *Impl) InsertUnsuccessfulTweetResponse(ctx context.Context,
 	excerpt Excerpt,
 	unsucessfullResponse twitter.TweetError,
 ) error {
-	conn, err := pgx.Connect(ctx, repository.connectionString)
+	conn, err := repository.connect(ctx)
 	if err != nil {
-		return fmt.Errorf(`something wrong happened while acquiring 
-		connection to the database while trying to insert successful tweet response: %w`,
-			err)
+		return fmt.Errorf("something wrong happened while acquiring connection to the database while trying to insert unsuccessful tweet response: %w", err)
 	}
+	defer conn.Close(ctx)
 	_, err = conn.Exec(ctx,
 		`INSERT INTO error_tweet_response (post_failed_on, title, type, detail, status, failed_excerpt) 
 		VALUES ($1, $2, $3, $4, $5, $6)`,
@@ -154,8 +438,154 @@ func (repository *Impl) InsertUnsuccessfulTweetResponse(ctx context.Context,
 		excerpt.Excerpt,
 	)
 	if err != nil {
-		return fmt.Errorf("something wrong happened while inserting successful tweet response: %w", err)
+		return fmt.Errorf("something wrong happened while inserting unsuccessful tweet response: %w", err)
+	}
+	repository.logger.Debugw("recorded the failed post", "excerpt_id", excerpt.ID, "status", unsucessfullResponse.Status)
+	return nil
+}
+
+func (repository *Impl) GetPostingHistory(ctx context.Context, limit int) ([]PostedExcerpt, error) {
+	return repository.queryPosts(ctx, `WHERE s.retracted_on IS NULL ORDER BY s.posted_on DESC LIMIT $1`, limit)
+}
+
+func (repository *Impl) GetFailedPosts(ctx context.Context, limit int) ([]FailedPost, error) {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	rows, err := conn.Query(ctx, `SELECT COALESCE(e.id, ''), f.failed_excerpt, COALESCE(f.title, ''),
+		COALESCE(f.type, ''), COALESCE(f.detail, ''), COALESCE(f.status, 0), f.post_failed_on
+		FROM error_tweet_response f LEFT JOIN excerpts e ON e.excerpt = f.failed_excerpt
+		ORDER BY f.post_failed_on DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while fetching failed posts: %w", err)
+	}
+	failures, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (FailedPost, error) {
+		var f FailedPost
+		err := row.Scan(&f.ExcerptID, &f.Excerpt, &f.Title, &f.Type, &f.Detail, &f.Status, &f.FailedOn)
+		return f, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while scanning failed posts: %w", err)
+	}
+	return failures, nil
+}
+
+func (repository *Impl) FindPostsToRetract(ctx context.Context, id string) ([]PostedExcerpt, error) {
+	return repository.queryPosts(ctx, `WHERE s.retracted_on IS NULL
+		AND (e.id = $1 OR s.tweet_id = $1 OR s.thread_tweet_ids @> jsonb_build_array($1::text))
+		ORDER BY s.posted_on`,
+		id,
+	)
+}
+
+func (repository *Impl) queryPosts(ctx context.Context, condition string, args ...any) ([]PostedExcerpt, error) {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	rows, err := conn.Query(ctx, `SELECT s.posted_on, s.tweet_id, COALESCE(s.thread_tweet_ids, '[]'::jsonb),
+		e.id, e.series, p.name, c.name, e.excerpt, e.dialogue, `+selectSpeakers+`, `+selectTags+`
+		FROM successful_tweet_response s JOIN excerpts e ON e.excerpt = s.tweeted_excerpt
+		JOIN chapters c ON c.id = e.chapter_id JOIN parts p ON p.id = c.part_id `+condition,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while fetching posts: %w", err)
+	}
+	posts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (PostedExcerpt, error) {
+		var p PostedExcerpt
+		err := row.Scan(&p.PostedOn, &p.TweetID, &p.ThreadTweetIDs,
+			&p.Excerpt.ID, &p.Excerpt.Series, &p.Excerpt.Part, &p.Excerpt.Chapter, &p.Excerpt.Excerpt,
+			&p.Excerpt.Dialogue, &p.Excerpt.Speakers, &p.Excerpt.Tags)
+		return p, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while scanning posts: %w", err)
+	}
+	return posts, nil
+}
+
+func (repository *Impl) MarkPostRetracted(ctx context.Context, tweetID string, reason string) error {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	tag, err := conn.Exec(ctx, `UPDATE successful_tweet_response SET retracted_on = $1, retraction_reason = $2
+		WHERE tweet_id = $3 AND retracted_on IS NULL`,
+		time.Now(), reason, tweetID,
+	)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while marking post %s as retracted: %w", tweetID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("there is no post with tweet %s left to retract", tweetID)
+	}
+	repository.logger.Infow("marked post as retracted", "tweet_id", tweetID, "reason", reason)
+	return nil
+}
+
+func (repository *Impl) InsertDryRunTweet(ctx context.Context, tweet twitter.Tweet, res twitter.SucessfullTweetResponse) error {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	var inReplyTo *string
+	if tweet.Reply != nil {
+		inReplyTo = &tweet.Reply.InReplyToTweetID
+	}
+	var mediaIDs []string
+	if tweet.Media != nil {
+		mediaIDs = tweet.Media.MediaIDs
+	}
+	_, err = conn.Exec(ctx, `INSERT INTO
+		dry_run_tweet (rendered_on, tweet_id, in_reply_to_tweet_id, text, media_ids)
+		VALUES ($1, $2, $3, $4, $5)`,
+		time.Now(), res.Data.ID, inReplyTo, tweet.Text, mediaIDs,
+	)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while inserting dry run tweet: %w", err)
+	}
+	return nil
+}
+
+func (repository *Impl) LoadOAuth2Token(ctx context.Context) (*oauth2.Token, error) {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	var token oauth2.Token
+	err = conn.QueryRow(ctx, `SELECT access_token, refresh_token, token_type, expiry FROM oauth2_token WHERE id = 1`).
+		Scan(&token.AccessToken, &token.RefreshToken, &token.TokenType, &token.Expiry)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, twitter.ErrNoToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while loading the oauth2 token: %w", err)
+	}
+	return &token, nil
+}
+
+func (repository *Impl) SaveOAuth2Token(ctx context.Context, token *oauth2.Token) error {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	_, err = conn.Exec(ctx, `INSERT INTO oauth2_token (id, access_token, refresh_token, token_type, expiry, updated_on)
+		VALUES (1, $1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET access_token = $1, refresh_token = $2, token_type = $3, expiry = $4, updated_on = $5`,
+		token.AccessToken, token.RefreshToken, token.TokenType, token.Expiry, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while saving the oauth2 token: %w", err)
 	}
-	repository.logger.Infof("inserted the successful tweet response for excerpt %s", excerpt.Excerpt)
 	return nil
 }
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestImpl_CreateTablesIfNotExistsDatabaseUnavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	_ = listener.Close()
+	repository := New(context.Background(), Config{User: "postgres", Host: "127.0.0.1", Port: port, Database: "max_payne"},
+		zap.NewNop().Sugar())
+	err = repository.CreateTablesIfNotExists(context.Background())
+	if !errors.Is(err, ErrDatabaseUnavailable) {
+		t.Fatalf("expected %v, got %v", ErrDatabaseUnavailable, err)
+	}
+}
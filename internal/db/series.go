@@ -0,0 +1,151 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Series int
+
+const (
+	Unspecified Series = iota
+	Original
+	TheFallOfMaxPayne
+	MaxPayne3
+)
+
+// SeriesInfo describes a series, Name is the identifier accepted in sources besides the number and
+// DisplayName is the one used when attributing an excerpt
+type SeriesInfo struct {
+	Series      Series
+	Name        string
+	Title       string
+	DisplayName string
+	Year        int
+}
+
+var (
+	seriesMu       sync.RWMutex
+	seriesRegistry = map[Series]SeriesInfo{
+		Original: {
+			Series: Original, Name: "max-payne", Title: "Max Payne", DisplayName: "Max Payne", Year: 2001,
+		},
+		TheFallOfMaxPayne: {
+			Series: TheFallOfMaxPayne, Name: "the-fall-of-max-payne", Title: "Max Payne 2: The Fall of Max Payne",
+			DisplayName: "The Fall of Max Payne", Year: 2003,
+		},
+		MaxPayne3: {
+			Series: MaxPayne3, Name: "max-payne-3", Title: "Max Payne 3", DisplayName: "Max Payne 3", Year: 2012,
+		},
+	}
+)
+
+// RegisterSeries adds a series or replaces the metadata of an already registered one
+func RegisterSeries(info SeriesInfo) {
+	seriesMu.Lock()
+	defer seriesMu.Unlock()
+	seriesRegistry[info.Series] = info
+}
+
+// RegisteredSeries returns the metadata of every known series ordered by number
+func RegisteredSeries() []SeriesInfo {
+	seriesMu.RLock()
+	defer seriesMu.RUnlock()
+	infos := make([]SeriesInfo, 0, len(seriesRegistry))
+	for _, info := range seriesRegistry {
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Series < infos[j].Series })
+	return infos
+}
+
+// ParseSeries accepts the number of a series, its name, title or display name, ignoring case
+func ParseSeries(s string) (Series, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return Series(n), nil
+	}
+	seriesMu.RLock()
+	defer seriesMu.RUnlock()
+	for series, info := range seriesRegistry {
+		if strings.EqualFold(s, info.Name) || strings.EqualFold(s, info.Title) || strings.EqualFold(s, info.DisplayName) {
+			return series, nil
+		}
+	}
+	return Unspecified, fmt.Errorf("unknown series %q", s)
+}
+
+func (s Series) Info() (SeriesInfo, bool) {
+	seriesMu.RLock()
+	defer seriesMu.RUnlock()
+	info, ok := seriesRegistry[s]
+	return info, ok
+}
+
+// Valid reports whether s is a registered series
+func (s Series) Valid() bool {
+	_, ok := s.Info()
+	return ok
+}
+
+func (s Series) String() string {
+	if info, ok := s.Info(); ok {
+		return info.DisplayName
+	}
+	return fmt.Sprintf("Series(%d)", int(s))
+}
+
+// MarshalJSON keeps the number so sources stay compatible with the ones written before series had names
+func (s Series) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(s))
+}
+
+func (s *Series) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		*s = Series(n)
+		return nil
+	}
+	var name string
+	err := json.Unmarshal(b, &name)
+	if err != nil {
+		return fmt.Errorf("series must be a number or a name: %w", err)
+	}
+	*s, err = ParseSeries(name)
+	return err
+}
+
+func (s Series) MarshalYAML() (any, error) {
+	return int(s), nil
+}
+
+func (s *Series) UnmarshalYAML(node *yaml.Node) error {
+	var err error
+	*s, err = ParseSeries(node.Value)
+	return err
+}
+
+func (s Series) Value() (driver.Value, error) {
+	return int64(s), nil
+}
+
+func (s *Series) Scan(src any) error {
+	switch v := src.(type) {
+	case int64:
+		*s = Series(v)
+	case int32:
+		*s = Series(v)
+	case nil:
+		*s = Unspecified
+	default:
+		return fmt.Errorf("cannot scan %T into a series", src)
+	}
+	return nil
+}
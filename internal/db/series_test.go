@@ -0,0 +1,45 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSeries_Unmarshal(t *testing.T) {
+	tests := []struct {
+		json, yaml string
+		expected   Series
+	}{
+		{json: `1`, yaml: `1`, expected: Original},
+		{json: `"the-fall-of-max-payne"`, yaml: `the-fall-of-max-payne`, expected: TheFallOfMaxPayne},
+		{json: `"Max Payne 3"`, yaml: `Max Payne 3`, expected: MaxPayne3},
+	}
+	for _, tt := range tests {
+		var fromJSON, fromYAML Series
+		if err := json.Unmarshal([]byte(tt.json), &fromJSON); err != nil || fromJSON != tt.expected {
+			t.Fatalf("expected %s from json %s, got %s: %v", tt.expected, tt.json, fromJSON, err)
+		}
+		if err := yaml.Unmarshal([]byte(tt.yaml), &fromYAML); err != nil || fromYAML != tt.expected {
+			t.Fatalf("expected %s from yaml %s, got %s: %v", tt.expected, tt.yaml, fromYAML, err)
+		}
+	}
+	var s Series
+	if err := json.Unmarshal([]byte(`"Max Payne 4"`), &s); err == nil {
+		t.Fatalf("expected an unknown series name to be rejected")
+	}
+	if err := json.Unmarshal([]byte(`7`), &s); err != nil || s.Valid() {
+		t.Fatalf("expected an unknown series number to decode but be invalid, got %s: %v", s, err)
+	}
+	b, err := json.Marshal(Excerpt{Series: TheFallOfMaxPayne})
+	if err != nil {
+		t.Fatalf("failed to marshal excerpt: %v", err)
+	}
+	var e struct {
+		Series int `json:"series"`
+	}
+	if err := json.Unmarshal(b, &e); err != nil || e.Series != 2 {
+		t.Fatalf("expected series to be marshalled as its number, got %s", b)
+	}
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Stats struct {
+	ActiveExcerpts   int
+	InactiveExcerpts int
+	Posts            int
+	RetractedPosts   int
+	FailedPosts      int
+	RejectedExcerpts int
+	// LastPostedOn is the zero time when nothing was posted yet
+	LastPostedOn time.Time
+}
+
+func (repository *Impl) GetStats(ctx context.Context) (Stats, error) {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return Stats{}, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	var stats Stats
+	var lastPostedOn *time.Time
+	err = conn.QueryRow(ctx, `SELECT
+		(SELECT count(*) FROM excerpts WHERE active),
+		(SELECT count(*) FROM excerpts WHERE NOT active),
+		(SELECT count(*) FROM successful_tweet_response WHERE retracted_on IS NULL),
+		(SELECT count(*) FROM successful_tweet_response WHERE retracted_on IS NOT NULL),
+		(SELECT count(*) FROM error_tweet_response),
+		(SELECT count(*) FROM rejected_excerpts),
+		(SELECT max(posted_on) FROM successful_tweet_response)`,
+	).Scan(&stats.ActiveExcerpts, &stats.InactiveExcerpts, &stats.Posts, &stats.RetractedPosts, &stats.FailedPosts,
+		&stats.RejectedExcerpts, &lastPostedOn)
+	if err != nil {
+		return Stats{}, fmt.Errorf("something wrong happened while computing stats: %w", err)
+	}
+	if lastPostedOn != nil {
+		stats.LastPostedOn = *lastPostedOn
+	}
+	return stats, nil
+}
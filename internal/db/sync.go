@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// SyncSummary is the difference between the active corpus before and after a sync
+type SyncSummary struct {
+	Added     int
+	Changed   int
+	Removed   int
+	Unchanged int
+}
+
+func (s SyncSummary) String() string {
+	return fmt.Sprintf("%d added, %d changed, %d removed, %d unchanged", s.Added, s.Changed, s.Removed, s.Unchanged)
+}
+
+// SyncExcerpts makes the excerpts the active corpus in a single transaction, excerpts missing from it are
+// deactivated rather than deleted since their posting history references them. Excerpts are matched by ID, so
+// editing the text of an excerpt counts as a change only when the corpus gives its ID, otherwise the edited excerpt
+// is added and the previous one removed.
+func (repository *Impl) SyncExcerpts(ctx context.Context, excerpts []Excerpt) (SyncSummary, error) {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return SyncSummary{}, fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return SyncSummary{}, fmt.Errorf("something wrong happened while starting transaction for syncing excerpts: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	rows, err := tx.Query(ctx, `SELECT id, COALESCE(series, 0), COALESCE(chapter_id, 0), excerpt,
+		COALESCE((SELECT array_agg(s.character_id ORDER BY s.slot) FROM excerpt_speakers s WHERE s.excerpt_id = e.id), '{}')
+		FROM excerpts e WHERE active`)
+	if err != nil {
+		return SyncSummary{}, fmt.Errorf("something wrong happened while fetching the active excerpts: %w", err)
+	}
+	active := make(map[string]Excerpt)
+	var e Excerpt
+	_, err = pgx.ForEachRow(rows, []any{&e.ID, &e.Series, &e.ChapterID, &e.Excerpt, &e.SpeakerIDs}, func() error {
+		active[e.ID] = e
+		// the next row must not be scanned into the speakers of this one
+		e.SpeakerIDs = nil
+		return nil
+	})
+	if err != nil {
+		return SyncSummary{}, fmt.Errorf("something wrong happened while scanning the active excerpts: %w", err)
+	}
+	var summary SyncSummary
+	batch := &pgx.Batch{}
+	ids := make([]string, 0, len(excerpts))
+	for i := range excerpts {
+		err = queueExcerptUpsert(batch, &excerpts[i])
+		if err != nil {
+			return SyncSummary{}, err
+		}
+		ids = append(ids, excerpts[i].ID)
+		previous, ok := active[excerpts[i].ID]
+		switch {
+		case !ok:
+			summary.Added++
+		case !Unchanged(previous, excerpts[i]):
+			summary.Changed++
+		default:
+			summary.Unchanged++
+		}
+	}
+	err = tx.SendBatch(ctx, batch).Close()
+	if err != nil {
+		return SyncSummary{}, fmt.Errorf("something wrong happened while upserting synced excerpts: %w", err)
+	}
+	tag, err := tx.Exec(ctx, `UPDATE excerpts SET active = false WHERE active AND id <> ALL($1)`, ids)
+	if err != nil {
+		return SyncSummary{}, fmt.Errorf("something wrong happened while deactivating removed excerpts: %w", err)
+	}
+	summary.Removed = int(tag.RowsAffected())
+	err = tx.Commit(ctx)
+	if err != nil {
+		return SyncSummary{}, fmt.Errorf("something wrong happened while committing synced excerpts: %w", err)
+	}
+	return summary, nil
+}
+
+// Unchanged reports whether syncing excerpt over previous leaves what gets posted as is, that is the series, chapter,
+// text and characters of the excerpt
+func Unchanged(previous, excerpt Excerpt) bool {
+	return previous.Series == excerpt.Series && previous.ChapterID == excerpt.ChapterID &&
+		previous.Excerpt == excerpt.Excerpt && slices.Equal(previous.SpeakerIDs, excerpt.SpeakerIDs)
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestUnchanged(t *testing.T) {
+	previous := Excerpt{Series: Original, ChapterID: 7, Excerpt: "They were all dead.", SpeakerIDs: []int{3}}
+	tests := []struct {
+		name      string
+		excerpt   Excerpt
+		unchanged bool
+	}{
+		{name: "same", excerpt: previous, unchanged: true},
+		{name: "tags only", excerpt: Excerpt{Series: Original, ChapterID: 7, Excerpt: "They were all dead.",
+			SpeakerIDs: []int{3}, Tags: []string{"noir"}}, unchanged: true},
+		{name: "chapter", excerpt: Excerpt{Series: Original, ChapterID: 8, Excerpt: "They were all dead.", SpeakerIDs: []int{3}}},
+		{name: "text", excerpt: Excerpt{Series: Original, ChapterID: 7, Excerpt: "They were all dead!", SpeakerIDs: []int{3}}},
+		{name: "characters", excerpt: Excerpt{Series: Original, ChapterID: 7, Excerpt: "They were all dead."}},
+	}
+	for _, tt := range tests {
+		if Unchanged(previous, tt.excerpt) != tt.unchanged {
+			t.Errorf("%s: expected unchanged to be %v", tt.name, tt.unchanged)
+		}
+	}
+}
+
+func TestQueueExcerptUpsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		conflict string
+		editable bool
+	}{
+		{name: "explicit id", id: "mp1-prologue-1", conflict: "ON CONFLICT (id)", editable: true},
+		{name: "derived id", conflict: "ON CONFLICT (excerpt)"},
+	}
+	for _, tt := range tests {
+		batch := &pgx.Batch{}
+		e := Excerpt{ID: tt.id, Series: Original, ChapterID: 7, Excerpt: "They were all dead!"}
+		err := queueExcerptUpsert(batch, &e)
+		if err != nil {
+			t.Fatalf("%s: failed to queue the upsert: %v", tt.name, err)
+		}
+		upsert := batch.QueuedQueries[0]
+		if !strings.Contains(upsert.SQL, tt.conflict) || strings.Contains(upsert.SQL, "excerpt = EXCLUDED.excerpt") != tt.editable {
+			t.Fatalf("%s: expected the upsert to match on %s and update the text %v, got %s", tt.name, tt.conflict,
+				tt.editable, upsert.SQL)
+		}
+		if tt.id == "" && e.ID != NewExcerptID(e.Excerpt) || tt.id != "" && e.ID != tt.id {
+			t.Fatalf("%s: unexpected id %s", tt.name, e.ID)
+		}
+	}
+}
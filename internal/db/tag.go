@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// NormalizeTag lowercases the tag and collapses its whitespace so "New York  Winter" and "new york winter" are the same tag
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+func (repository *Impl) TagExcerpt(ctx context.Context, excerptID string, tags ...string) error {
+	return repository.updateTags(ctx, excerptID, tags, func(tx pgx.Tx, tag string) error {
+		_, err := tx.Exec(ctx, `INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, tag)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `INSERT INTO excerpt_tags (excerpt_id, tag_id) SELECT $1, id FROM tags WHERE name = $2
+			ON CONFLICT DO NOTHING`,
+			excerptID, tag,
+		)
+		return err
+	})
+}
+
+func (repository *Impl) UntagExcerpt(ctx context.Context, excerptID string, tags ...string) error {
+	return repository.updateTags(ctx, excerptID, tags, func(tx pgx.Tx, tag string) error {
+		_, err := tx.Exec(ctx, `DELETE FROM excerpt_tags
+			WHERE excerpt_id = $1 AND tag_id = (SELECT id FROM tags WHERE name = $2)`,
+			excerptID, tag,
+		)
+		return err
+	})
+}
+
+// updateTags applies update to every normalized tag in a single transaction once the excerpt is known to exist
+func (repository *Impl) updateTags(ctx context.Context, excerptID string, tags []string, update func(tx pgx.Tx, tag string) error) error {
+	conn, err := repository.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while acquiring connection to the database: %w", err)
+	}
+	defer conn.Close(ctx)
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("something wrong happened while starting transaction for updating tags: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM excerpts WHERE id = $1)`, excerptID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while looking up excerpt %s: %w", excerptID, err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrExcerptNotFound, excerptID)
+	}
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" {
+			continue
+		}
+		err = update(tx, tag)
+		if err != nil {
+			return fmt.Errorf("something wrong happened while updating tag %q of excerpt %s: %w", tag, excerptID, err)
+		}
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while committing tags of excerpt %s: %w", excerptID, err)
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/publisher"
+	"github.com/aaegamysta/listen-2-max-payne/internal/logging"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 500
+)
+
+type Interface interface {
+	// Handler serves the JSON endpoints relative to wherever it is mounted, every request needs the bearer token
+	Handler() http.Handler
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	// redactor redacts the secrets from the errors served
+	redactor   *logging.Redactor
+	cfg        Config
+	repository db.Interface
+	publisher  publisher.Interface
+}
+
+func New(_ context.Context,
+	cfg Config,
+	logger *zap.SugaredLogger,
+	redactor *logging.Redactor,
+	repository db.Interface,
+	publisher publisher.Interface,
+) Interface {
+	return &impl{
+		logger:     logger,
+		redactor:   redactor,
+		cfg:        cfg,
+		repository: repository,
+		publisher:  publisher,
+	}
+}
+
+// errBadRequest is wrapped in the errors of invalid requests
+var errBadRequest = errors.New("bad request")
+
+type excerptResponse struct {
+	db.Excerpt
+	Source    string `json:"source,omitempty"`
+	Disabled  bool   `json:"disabled"`
+	Removed   bool   `json:"removed"`
+	Retracted bool   `json:"retracted"`
+}
+
+type postResponse struct {
+	TweetID        string          `json:"tweetId"`
+	ThreadTweetIDs []string        `json:"threadTweetIds,omitempty"`
+	PostedOn       time.Time       `json:"postedOn"`
+	Excerpt        excerptResponse `json:"excerpt"`
+}
+
+type failureResponse struct {
+	ExcerptID string    `json:"excerptId,omitempty"`
+	Excerpt   string    `json:"excerpt"`
+	Title     string    `json:"title"`
+	Type      string    `json:"type"`
+	Detail    string    `json:"detail"`
+	Status    int       `json:"status"`
+	FailedOn  time.Time `json:"failedOn"`
+}
+
+type scheduleResponse struct {
+	Paused     bool       `json:"paused"`
+	Skipping   int        `json:"skipping"`
+	NextPostAt *time.Time `json:"nextPostAt,omitempty"`
+}
+
+type postRequest struct {
+	// ExcerptID is optional, the excerpt the schedule would post next is posted when it is empty
+	ExcerptID string `json:"excerptId"`
+	// Force posts the excerpt even when it is disabled, removed from the corpus or retracted
+	Force bool `json:"force"`
+}
+
+func (i *impl) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /excerpts", i.handle(i.listExcerpts))
+	mux.HandleFunc("GET /excerpts/{id}", i.handle(i.getExcerpt))
+	mux.HandleFunc("POST /excerpts/{id}/disable", i.handle(i.setDisabled(true)))
+	mux.HandleFunc("POST /excerpts/{id}/enable", i.handle(i.setDisabled(false)))
+	mux.HandleFunc("GET /history", i.handle(i.history))
+	mux.HandleFunc("GET /failures", i.handle(i.failures))
+	mux.HandleFunc("POST /posts", i.handle(i.postNow))
+	mux.HandleFunc("GET /schedule", i.handle(i.schedule))
+	mux.HandleFunc("POST /schedule/pause", i.handle(i.control(i.publisher.Pause)))
+	mux.HandleFunc("POST /schedule/resume", i.handle(i.control(i.publisher.Resume)))
+	mux.HandleFunc("POST /schedule/skip", i.handle(i.control(i.publisher.Skip)))
+	return i.authorize(mux)
+}
+
+// authorize rejects the requests without the configured bearer token, comparing in constant time
+func (i *impl) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || i.cfg.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(i.cfg.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing or invalid bearer token"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handle writes what h returns as JSON, or its error with the status matching it
+func (i *impl) handle(h func(r *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := h(r)
+		status := http.StatusOK
+		var refused twitter.TweetError
+		switch {
+		case err == nil:
+		case errors.Is(err, errBadRequest):
+			status = http.StatusBadRequest
+		case errors.Is(err, db.ErrExcerptNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, publisher.ErrExcerptInactive):
+			status = http.StatusConflict
+		case errors.As(err, &refused):
+			// Twitter refused the post, which is recorded, so it is reported as a failure of the upstream
+			status = http.StatusBadGateway
+		case errors.Is(err, db.ErrDatabaseUnavailable):
+			status = http.StatusServiceUnavailable
+		default:
+			status = http.StatusInternalServerError
+			i.logger.Errorw("failed to serve an admin request", "method", r.Method, "path", r.URL.Path, "error", err)
+		}
+		if err != nil {
+			body = map[string]string{"error": i.redactor.Redact(err.Error())}
+		}
+		writeJSON(w, status, body)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func (i *impl) listExcerpts(r *http.Request) (any, error) {
+	query := r.URL.Query()
+	filter := db.ExcerptFilter{
+		Part:            query.Get("part"),
+		Chapter:         query.Get("chapter"),
+		Character:       query.Get("character"),
+		Tags:            query["tag"],
+		Query:           query.Get("q"),
+		IncludeDisabled: true,
+	}
+	if series := query.Get("series"); series != "" {
+		var err error
+		filter.Series, err = db.ParseSeries(series)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", errBadRequest, err)
+		}
+	}
+	excerpts, err := i.repository.ListExcerpts(r.Context(), filter)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]excerptResponse, 0, len(excerpts))
+	for _, e := range excerpts {
+		res = append(res, newExcerptResponse(e))
+	}
+	return res, nil
+}
+
+func (i *impl) getExcerpt(r *http.Request) (any, error) {
+	e, err := i.repository.GetExcerpt(r.Context(), r.PathValue("id"))
+	if err != nil {
+		return nil, err
+	}
+	return newExcerptResponse(e), nil
+}
+
+func (i *impl) setDisabled(disabled bool) func(r *http.Request) (any, error) {
+	return func(r *http.Request) (any, error) {
+		id := r.PathValue("id")
+		err := i.repository.SetExcerptDisabled(r.Context(), id, disabled)
+		if err != nil {
+			return nil, err
+		}
+		i.logger.Infow("set whether excerpt is disabled", "excerpt_id", id, "disabled", disabled)
+		return i.getExcerpt(r)
+	}
+}
+
+func (i *impl) history(r *http.Request) (any, error) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		return nil, err
+	}
+	posts, err := i.repository.GetPostingHistory(r.Context(), limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]postResponse, 0, len(posts))
+	for _, p := range posts {
+		res = append(res, postResponse{
+			TweetID:        p.TweetID,
+			ThreadTweetIDs: p.ThreadTweetIDs,
+			PostedOn:       p.PostedOn,
+			Excerpt:        newExcerptResponse(p.Excerpt),
+		})
+	}
+	return res, nil
+}
+
+func (i *impl) failures(r *http.Request) (any, error) {
+	limit, err := parseLimit(r)
+	if err != nil {
+		return nil, err
+	}
+	failures, err := i.repository.GetFailedPosts(r.Context(), limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]failureResponse, 0, len(failures))
+	for _, f := range failures {
+		res = append(res, failureResponse(f))
+	}
+	return res, nil
+}
+
+func (i *impl) postNow(r *http.Request) (any, error) {
+	var req postRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: %w", errBadRequest, err)
+	}
+	// the post is recorded in the posting history even when the client disconnects before it completes
+	e, err := i.publisher.PostNow(context.WithoutCancel(r.Context()), req.ExcerptID, req.Force)
+	if err != nil {
+		return nil, err
+	}
+	return newExcerptResponse(e), nil
+}
+
+func (i *impl) schedule(_ *http.Request) (any, error) {
+	status := i.publisher.Status()
+	res := scheduleResponse{Paused: status.Paused, Skipping: status.Skipping}
+	if !status.NextPostAt.IsZero() {
+		res.NextPostAt = &status.NextPostAt
+	}
+	return res, nil
+}
+
+// control applies an action on the schedule and returns its new state
+func (i *impl) control(action func()) func(r *http.Request) (any, error) {
+	return func(r *http.Request) (any, error) {
+		action()
+		return i.schedule(r)
+	}
+}
+
+func newExcerptResponse(e db.Excerpt) excerptResponse {
+	return excerptResponse{Excerpt: e, Source: e.Source, Disabled: e.Disabled, Removed: e.Removed, Retracted: e.Retracted}
+}
+
+func parseLimit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > maxLimit {
+		return 0, fmt.Errorf("%w: limit must be a number between 1 and %d", errBadRequest, maxLimit)
+	}
+	return limit, nil
+}
@@ -0,0 +1,153 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db/dbtest"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/publisher"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter/twittertest"
+	"go.uber.org/zap"
+)
+
+const testToken = "dead-in-new-york-winter"
+
+type fakePublisher struct {
+	publisher.Interface
+	status publisher.Status
+	posted []string
+	// inactive is the ID of the excerpt posting is refused for unless forced
+	inactive string
+	// refusal is returned by PostNow when set, as when Twitter refused the post
+	refusal error
+}
+
+func (f *fakePublisher) PostNow(ctx context.Context, excerptID string, force bool) (db.Excerpt, error) {
+	if ctx.Err() != nil {
+		return db.Excerpt{}, ctx.Err()
+	}
+	if excerptID != "" && excerptID == f.inactive && !force {
+		return db.Excerpt{}, publisher.ErrExcerptInactive
+	}
+	if f.refusal != nil {
+		return db.Excerpt{}, f.refusal
+	}
+	f.posted = append(f.posted, excerptID)
+	return db.Excerpt{ID: excerptID}, nil
+}
+
+func (f *fakePublisher) Pause() {
+	f.status.Paused = true
+}
+
+func (f *fakePublisher) Status() publisher.Status {
+	return f.status
+}
+
+func newTestHandler() (http.Handler, *dbtest.Repository, *fakePublisher) {
+	repository := &dbtest.Repository{Excerpts: []db.Excerpt{
+		{ID: "4f1c", Series: 1, Excerpt: "They were all dead.", Tags: []string{"winter"}},
+	}}
+	pub := &fakePublisher{}
+	a := New(context.Background(), Config{Token: testToken}, zap.NewNop().Sugar(), nil, repository, pub)
+	return a.Handler(), repository, pub
+}
+
+func serve(h http.Handler, method string, target string, body string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return res
+}
+
+func TestHandler_Unauthorized(t *testing.T) {
+	h, _, _ := newTestHandler()
+	for _, token := range []string{"", "wrong-token-of-the-same-length"} {
+		res := serve(h, http.MethodGet, "/excerpts", "", token)
+		if res.Code != http.StatusUnauthorized || res.Header().Get("WWW-Authenticate") == "" {
+			t.Fatalf("expected the request with token %q to be rejected, got %d", token, res.Code)
+		}
+	}
+}
+
+func TestHandler_ListExcerpts(t *testing.T) {
+	h, repository, _ := newTestHandler()
+	res := serve(h, http.MethodGet, "/excerpts?series=1&tag=winter&tag=dreams&q=dead", "", testToken)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body)
+	}
+	expected := db.ExcerptFilter{Series: 1, Tags: []string{"winter", "dreams"}, Query: "dead", IncludeDisabled: true}
+	if !reflect.DeepEqual(repository.Filters[0], expected) {
+		t.Fatalf("expected filter %+v, got %+v", expected, repository.Filters[0])
+	}
+	var excerpts []map[string]any
+	err := json.Unmarshal(res.Body.Bytes(), &excerpts)
+	if err != nil || len(excerpts) != 1 || excerpts[0]["id"] != "4f1c" || excerpts[0]["disabled"] != false {
+		t.Fatalf("unexpected excerpts %s: %v", res.Body, err)
+	}
+	res = serve(h, http.MethodGet, "/excerpts?series=max-payne-9", "", testToken)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown series, got %d", res.Code)
+	}
+}
+
+func TestHandler_DisableExcerpt(t *testing.T) {
+	h, repository, _ := newTestHandler()
+	res := serve(h, http.MethodPost, "/excerpts/4f1c/disable", "", testToken)
+	if res.Code != http.StatusOK || !repository.Excerpts[0].Disabled || !strings.Contains(res.Body.String(), `"disabled":true`) {
+		t.Fatalf("expected the excerpt to be disabled, got %d: %s", res.Code, res.Body)
+	}
+	res = serve(h, http.MethodPost, "/excerpts/missing/enable", "", testToken)
+	if res.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown excerpt, got %d", res.Code)
+	}
+}
+
+func TestHandler_Schedule(t *testing.T) {
+	h, _, pub := newTestHandler()
+	res := serve(h, http.MethodPost, "/posts", `{"excerptId": "4f1c"}`, testToken)
+	if res.Code != http.StatusOK || !reflect.DeepEqual(pub.posted, []string{"4f1c"}) {
+		t.Fatalf("expected the excerpt to be posted, got %d: %s", res.Code, res.Body)
+	}
+	res = serve(h, http.MethodPost, "/posts", "", testToken)
+	if res.Code != http.StatusOK || !reflect.DeepEqual(pub.posted, []string{"4f1c", ""}) {
+		t.Fatalf("expected the next excerpt to be posted, got %d: %s", res.Code, res.Body)
+	}
+	ctx, disconnect := context.WithCancel(context.Background())
+	disconnect()
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/posts", strings.NewReader(`{"excerptId": "4f1c"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if len(pub.posted) != 3 {
+		t.Fatalf("expected the excerpt to be posted after the client disconnected, got %v", pub.posted)
+	}
+	pub.inactive = "4f1c"
+	res = serve(h, http.MethodPost, "/posts", `{"excerptId": "4f1c"}`, testToken)
+	if res.Code != http.StatusConflict || len(pub.posted) != 3 {
+		t.Fatalf("expected posting an inactive excerpt to conflict, got %d: %s", res.Code, res.Body)
+	}
+	res = serve(h, http.MethodPost, "/posts", `{"excerptId": "4f1c", "force": true}`, testToken)
+	if res.Code != http.StatusOK || len(pub.posted) != 4 {
+		t.Fatalf("expected an inactive excerpt to be posted when forced, got %d: %s", res.Code, res.Body)
+	}
+	pub.refusal = fmt.Errorf("twitter refused to post excerpt 4f1c: %w", twittertest.ErrDuplicate)
+	res = serve(h, http.MethodPost, "/posts", `{"excerptId": "4f1c", "force": true}`, testToken)
+	if res.Code != http.StatusBadGateway || !strings.Contains(res.Body.String(), "duplicate content") {
+		t.Fatalf("expected a refused post to be reported as a bad gateway, got %d: %s", res.Code, res.Body)
+	}
+	pub.refusal = nil
+	res = serve(h, http.MethodPost, "/schedule/pause", "", testToken)
+	if res.Code != http.StatusOK || strings.TrimSpace(res.Body.String()) != `{"paused":true,"skipping":0}` {
+		t.Fatalf("expected the schedule to be paused, got %d: %s", res.Code, res.Body)
+	}
+}
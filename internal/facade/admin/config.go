@@ -0,0 +1,18 @@
+package admin
+
+import "fmt"
+
+const minTokenLength = 16
+
+type Config struct {
+	// Token is the bearer token every request has to carry, the admin API is not served when it is empty
+	Token string `yaml:"token" secret:"true"`
+}
+
+// Validate reports every invalid field at once, the whole section is optional
+func (c Config) Validate() error {
+	if c.Token != "" && len(c.Token) < minTokenLength {
+		return fmt.Errorf("token must be at least %d characters long", minTokenLength)
+	}
+	return nil
+}
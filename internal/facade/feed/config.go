@@ -0,0 +1,43 @@
+package feed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+type Config struct {
+	Title       string `yaml:"title"`
+	Description string `yaml:"description"`
+	Link        string `yaml:"link"`
+	Author      string `yaml:"author"`
+	// TweetURLFormat is used with fmt.Sprintf and the tweet ID to link each item to its tweet
+	TweetURLFormat string `yaml:"tweetURLFormat"`
+	Limit          int    `yaml:"limit"`
+	// OutputDir enables static generation of the feed files when it is not empty
+	OutputDir       string        `yaml:"outputDir"`
+	RefreshInterval time.Duration `yaml:"refreshInterval"`
+	// DialogueStyle is how dialogue is written out in feed items, the corpus markers are kept when it is empty
+	DialogueStyle db.DialogueStyle `yaml:"dialogueStyle"`
+}
+
+// Validate reports every invalid field at once, every field is optional
+func (c Config) Validate() error {
+	var errs []error
+	if c.TweetURLFormat != "" && !strings.Contains(c.TweetURLFormat, "%s") {
+		errs = append(errs, fmt.Errorf("tweetURLFormat %q has no %%s for the tweet ID", c.TweetURLFormat))
+	}
+	if c.Limit < 0 {
+		errs = append(errs, fmt.Errorf("limit %d must not be negative", c.Limit))
+	}
+	if c.RefreshInterval < 0 {
+		errs = append(errs, fmt.Errorf("refreshInterval %s must not be negative", c.RefreshInterval))
+	}
+	if !c.DialogueStyle.Valid() {
+		errs = append(errs, fmt.Errorf("dialogueStyle %q is not a known style", c.DialogueStyle))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,184 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit           = 50
+	defaultTweetURLFormat  = "https://x.com/i/status/%s"
+	defaultRefreshInterval = 10 * time.Minute
+)
+
+type Interface interface {
+	// Handler serves rss.xml, atom.xml and feed.json relative to wherever it is mounted
+	Handler() http.Handler
+	StartGeneratingFiles(ctx context.Context)
+	// StopGeneratingFiles waits for the generator to return once the context it was started with is done
+	StopGeneratingFiles(ctx context.Context) error
+}
+
+// item is the format agnostic representation of a posted excerpt every feed format is rendered from
+type item struct {
+	GUID      string
+	Title     string
+	Link      string
+	Content   string
+	Published time.Time
+}
+
+type format struct {
+	contentType string
+	render      func(cfg Config, items []item) ([]byte, error)
+}
+
+var formats = map[string]format{
+	"rss.xml":   {contentType: "application/rss+xml; charset=utf-8", render: renderRSS},
+	"atom.xml":  {contentType: "application/atom+xml; charset=utf-8", render: renderAtom},
+	"feed.json": {contentType: "application/feed+json; charset=utf-8", render: renderJSONFeed},
+}
+
+type impl struct {
+	logger     *zap.SugaredLogger
+	cfg        Config
+	repository db.Interface
+	// generating is closed once the generator returns, it is nil when no files are generated
+	generating chan struct{}
+}
+
+func New(_ context.Context, cfg Config, logger *zap.SugaredLogger, repository db.Interface) Interface {
+	if cfg.Limit <= 0 {
+		cfg.Limit = defaultLimit
+	}
+	if cfg.TweetURLFormat == "" {
+		cfg.TweetURLFormat = defaultTweetURLFormat
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = defaultRefreshInterval
+	}
+	return &impl{
+		logger:     logger,
+		cfg:        cfg,
+		repository: repository,
+	}
+}
+
+func (i *impl) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for name, f := range formats {
+		mux.HandleFunc("GET /"+name, func(w http.ResponseWriter, r *http.Request) {
+			b, err := i.render(r.Context(), f)
+			if err != nil {
+				i.logger.Errorw("failed to render feed", "format", name, "error", err)
+				http.Error(w, "failed to render feed", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", f.contentType)
+			_, _ = w.Write(b)
+		})
+	}
+	return mux
+}
+
+func (i *impl) StartGeneratingFiles(ctx context.Context) {
+	if i.cfg.OutputDir == "" {
+		return
+	}
+	i.generating = make(chan struct{})
+	go func() {
+		defer close(i.generating)
+		t := time.NewTicker(i.cfg.RefreshInterval)
+		defer t.Stop()
+		for {
+			err := i.writeFiles(ctx)
+			if err != nil {
+				i.logger.Errorw("failed to generate feed files, retrying on next refresh", "error", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+func (i *impl) StopGeneratingFiles(ctx context.Context) error {
+	if i.generating == nil {
+		return nil
+	}
+	select {
+	case <-i.generating:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("something wrong happened while waiting for the feed generator to stop: %w", ctx.Err())
+	}
+}
+
+func (i *impl) writeFiles(ctx context.Context) error {
+	err := os.MkdirAll(i.cfg.OutputDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while creating feed output directory: %w", err)
+	}
+	items, err := i.items(ctx)
+	if err != nil {
+		return err
+	}
+	for name, f := range formats {
+		b, err := f.render(i.cfg, items)
+		if err != nil {
+			return fmt.Errorf("something wrong happened while rendering %s: %w", name, err)
+		}
+		// write to a temporary file first so readers never observe a half written feed
+		path := filepath.Join(i.cfg.OutputDir, name)
+		err = os.WriteFile(path+".tmp", b, 0o644)
+		if err != nil {
+			return fmt.Errorf("something wrong happened while writing %s: %w", name, err)
+		}
+		err = os.Rename(path+".tmp", path)
+		if err != nil {
+			return fmt.Errorf("something wrong happened while replacing %s: %w", name, err)
+		}
+	}
+	i.logger.Infow("generated feed files", "items", len(items), "dir", i.cfg.OutputDir)
+	return nil
+}
+
+func (i *impl) render(ctx context.Context, f format) ([]byte, error) {
+	items, err := i.items(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return f.render(i.cfg, items)
+}
+
+func (i *impl) items(ctx context.Context) ([]item, error) {
+	history, err := i.repository.GetPostingHistory(ctx, i.cfg.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while fetching posting history for the feed: %w", err)
+	}
+	items := make([]item, 0, len(history))
+	for _, posted := range history {
+		items = append(items, newItem(i.cfg, posted))
+	}
+	return items, nil
+}
+
+func newItem(cfg Config, posted db.PostedExcerpt) item {
+	return item{
+		// the tweet ID never changes once posted so it makes for a GUID that is stable across feed regenerations
+		GUID:      "urn:listen-2-max-payne:tweet:" + posted.TweetID,
+		Title:     fmt.Sprintf("%s, %s, %s", posted.Excerpt.Series, posted.Excerpt.Part, posted.Excerpt.Chapter),
+		Link:      fmt.Sprintf(cfg.TweetURLFormat, posted.TweetID),
+		Content:   cfg.DialogueStyle.Render(posted.Excerpt),
+		Published: posted.PostedOn,
+	}
+}
@@ -0,0 +1,172 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"time"
+)
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func renderRSS(cfg Config, items []item) ([]byte, error) {
+	feed := rss{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       cfg.Title,
+			Link:        cfg.Link,
+			Description: cfg.Description,
+			Items:       make([]rssItem, 0, len(items)),
+		},
+	}
+	if len(items) > 0 {
+		feed.Channel.LastBuildDate = items[0].Published.Format(time.RFC1123Z)
+	}
+	for _, it := range items {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       it.Title,
+			Link:        it.Link,
+			Description: it.Content,
+			GUID:        rssGUID{IsPermaLink: false, Value: it.GUID},
+			PubDate:     it.Published.Format(time.RFC1123Z),
+		})
+	}
+	return marshalXML(feed)
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Author  *atomAuthor `xml:"author,omitempty"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Link      atomLink    `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+func renderAtom(cfg Config, items []item) ([]byte, error) {
+	feed := atomFeed{
+		ID:      cfg.Link,
+		Title:   cfg.Title,
+		Link:    atomLink{Href: cfg.Link},
+		Entries: make([]atomEntry, 0, len(items)),
+	}
+	if cfg.Author != "" {
+		feed.Author = &atomAuthor{Name: cfg.Author}
+	}
+	// an empty feed still needs a valid updated element so the epoch is used rather than the render time to keep output stable
+	updated := time.Unix(0, 0).UTC()
+	if len(items) > 0 {
+		updated = items[0].Published
+	}
+	feed.Updated = updated.Format(time.RFC3339)
+	for _, it := range items {
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        it.GUID,
+			Title:     it.Title,
+			Updated:   it.Published.Format(time.RFC3339),
+			Published: it.Published.Format(time.RFC3339),
+			Link:      atomLink{Href: it.Link},
+			Content:   atomContent{Type: "text", Value: it.Content},
+		})
+	}
+	return marshalXML(feed)
+}
+
+func marshalXML(v any) ([]byte, error) {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Authors     []jsonAuthor   `json:"authors,omitempty"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+	ContentText   string `json:"content_text"`
+	DatePublished string `json:"date_published"`
+}
+
+func renderJSONFeed(cfg Config, items []item) ([]byte, error) {
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       cfg.Title,
+		HomePageURL: cfg.Link,
+		Description: cfg.Description,
+		Items:       make([]jsonFeedItem, 0, len(items)),
+	}
+	if cfg.Author != "" {
+		feed.Authors = []jsonAuthor{{Name: cfg.Author}}
+	}
+	for _, it := range items {
+		feed.Items = append(feed.Items, jsonFeedItem{
+			ID:            it.GUID,
+			URL:           it.Link,
+			Title:         it.Title,
+			ContentText:   it.Content,
+			DatePublished: it.Published.Format(time.RFC3339),
+		})
+	}
+	return json.MarshalIndent(feed, "", "  ")
+}
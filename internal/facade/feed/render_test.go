@@ -0,0 +1,56 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+func testItems() []item {
+	cfg := Config{TweetURLFormat: defaultTweetURLFormat}
+	return []item{
+		newItem(cfg, db.PostedExcerpt{
+			Excerpt: db.Excerpt{
+				Series:  1,
+				Part:    "Part I: The American Dream",
+				Chapter: "Roscoe Street Station",
+				Excerpt: "The sun set fast, as if it was in a hurry to leave & get away.",
+			},
+			TweetID:  "1834562",
+			PostedOn: time.Date(2024, time.December, 24, 20, 0, 0, 0, time.UTC),
+		}),
+	}
+}
+
+func Test_renderFeeds(t *testing.T) {
+	cfg := Config{Title: "Listen to Max Payne", Link: "https://example.com"}
+	for name, f := range formats {
+		first, err := f.render(cfg, testItems())
+		if err != nil {
+			t.Fatalf("failed to render %s: %v", name, err)
+		}
+		second, err := f.render(cfg, testItems())
+		if err != nil {
+			t.Fatalf("failed to render %s: %v", name, err)
+		}
+		if string(first) != string(second) {
+			t.Fatalf("rendering %s twice produced different output", name)
+		}
+		if !strings.Contains(string(first), "urn:listen-2-max-payne:tweet:1834562") {
+			t.Fatalf("%s does not contain the item GUID: %s", name, first)
+		}
+		var v any
+		if strings.HasSuffix(name, ".json") {
+			err = json.Unmarshal(first, &v)
+		} else {
+			err = xml.Unmarshal(first, &v)
+		}
+		if err != nil {
+			t.Fatalf("%s is not well formed: %v", name, err)
+		}
+	}
+}
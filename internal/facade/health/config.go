@@ -0,0 +1,27 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Config struct {
+	// Timeout bounds each readiness check, 5s when zero
+	Timeout time.Duration `yaml:"timeout"`
+	// CredentialsInterval is how long the outcome of verifying the twitter credentials is reused, 2m when zero,
+	// the endpoint verifying them is rate limited so it cannot be called on every probe
+	CredentialsInterval time.Duration `yaml:"credentialsInterval"`
+}
+
+// Validate reports every invalid field at once, every field is optional
+func (c Config) Validate() error {
+	var errs []error
+	if c.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout %s must not be negative", c.Timeout))
+	}
+	if c.CredentialsInterval < 0 {
+		errs = append(errs, fmt.Errorf("credentialsInterval %s must not be negative", c.CredentialsInterval))
+	}
+	return errors.Join(errs...)
+}
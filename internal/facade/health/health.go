@@ -0,0 +1,198 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/publisher"
+	"github.com/aaegamysta/listen-2-max-payne/internal/logging"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTimeout             = 5 * time.Second
+	defaultCredentialsInterval = 2 * time.Minute
+	// missedTicks is how many ticks the publishing loop can miss before it is considered stuck, a tick that posts
+	// holds the loop for as long as the post takes
+	missedTicks = 3
+)
+
+const (
+	statusOK      = "ok"
+	statusFailing = "failing"
+)
+
+type Interface interface {
+	// Handler serves GET /healthz, whether the process is alive, and GET /readyz, whether the bot can post, relative
+	// to wherever it is mounted, neither needs authentication
+	Handler() http.Handler
+}
+
+type impl struct {
+	logger *zap.SugaredLogger
+	// redactor redacts the secrets from the errors of the checks served
+	redactor      *logging.Redactor
+	cfg           Config
+	repository    db.Interface
+	publisher     publisher.Interface
+	twitterClient twitter.Interface
+	// mu guards the outcome of the last verification of the credentials
+	mu         sync.Mutex
+	verifiedAt time.Time
+	user       twitter.User
+	verifyErr  error
+}
+
+func New(_ context.Context,
+	cfg Config,
+	logger *zap.SugaredLogger,
+	redactor *logging.Redactor,
+	repository db.Interface,
+	publisher publisher.Interface,
+	twitterClient twitter.Interface,
+) Interface {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.CredentialsInterval <= 0 {
+		cfg.CredentialsInterval = defaultCredentialsInterval
+	}
+	return &impl{
+		logger:        logger,
+		redactor:      redactor,
+		cfg:           cfg,
+		repository:    repository,
+		publisher:     publisher,
+		twitterClient: twitterClient,
+	}
+}
+
+type response struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+type checkResult struct {
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// check returns a detail of what it verified or why it failed
+type check struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+func (i *impl) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", i.serve(check{name: "publisher", run: i.checkPublisher}))
+	mux.HandleFunc("GET /readyz", i.serve(
+		check{name: "database", run: i.checkDatabase},
+		check{name: "corpus", run: i.checkCorpus},
+		check{name: "twitter", run: i.checkTwitter},
+	))
+	return mux
+}
+
+// serve runs the checks concurrently and responds with 503 when any of them fails
+func (i *impl) serve(checks ...check) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results := make([]checkResult, len(checks))
+		var wg sync.WaitGroup
+		for index, c := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[index] = i.run(r.Context(), c)
+			}()
+		}
+		wg.Wait()
+		res := response{Status: statusOK, Checks: make(map[string]checkResult, len(checks))}
+		status := http.StatusOK
+		for index, c := range checks {
+			res.Checks[c.name] = results[index]
+			if results[index].Status != statusOK {
+				res.Status = statusFailing
+				status = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(res)
+	}
+}
+
+func (i *impl) run(ctx context.Context, c check) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, i.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	detail, err := c.run(ctx)
+	result := checkResult{Status: statusOK, Detail: detail, Duration: time.Since(start).String()}
+	if err != nil {
+		i.logger.Warnw("health check failed", "check", c.name, "error", err)
+		result.Status = statusFailing
+		result.Error = i.redactor.Redact(err.Error())
+	}
+	return result
+}
+
+// checkPublisher fails when the publishing loop stopped ticking, it passes until publishing starts since importing
+// the corpus on start can take a while
+func (i *impl) checkPublisher(_ context.Context) (string, error) {
+	status := i.publisher.Status()
+	if status.TickPeriod == 0 {
+		return "publishing is not started yet", nil
+	}
+	since := time.Since(status.LastTickAt).Round(time.Second)
+	if since > missedTicks*status.TickPeriod {
+		return "", fmt.Errorf("the publishing loop last ticked %s ago, it ticks every %s", since, status.TickPeriod)
+	}
+	return fmt.Sprintf("last ticked %s ago", since), nil
+}
+
+func (i *impl) checkDatabase(ctx context.Context) (string, error) {
+	err := i.repository.Ping(ctx)
+	if err != nil {
+		return "", err
+	}
+	return "reachable", nil
+}
+
+func (i *impl) checkCorpus(ctx context.Context) (string, error) {
+	stats, err := i.repository.GetStats(ctx)
+	if err != nil {
+		return "", err
+	}
+	if stats.ActiveExcerpts == 0 {
+		return "", fmt.Errorf("the corpus is empty, %d excerpts are inactive", stats.InactiveExcerpts)
+	}
+	return fmt.Sprintf("%d active excerpts", stats.ActiveExcerpts), nil
+}
+
+// checkTwitter reuses the outcome of the last verification of the credentials for the configured interval, failed or
+// not
+func (i *impl) checkTwitter(ctx context.Context) (string, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.verifiedAt.IsZero() || time.Since(i.verifiedAt) >= i.cfg.CredentialsInterval {
+		user, err := i.twitterClient.VerifyCredentials(ctx)
+		if err != nil && ctx.Err() != nil {
+			// a probe that timed out says nothing about the credentials so it is not reused
+			return "", fmt.Errorf("something wrong happened while verifying the credentials: %w", err)
+		}
+		i.user, i.verifyErr, i.verifiedAt = user, err, time.Now()
+	}
+	if i.verifyErr != nil {
+		return "", fmt.Errorf("something wrong happened while verifying the credentials: %w", i.verifyErr)
+	}
+	return fmt.Sprintf("authenticated as @%s, verified at %s", i.user.Username, i.verifiedAt.Format(time.RFC3339)), nil
+}
@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db/dbtest"
+	"github.com/aaegamysta/listen-2-max-payne/internal/facade/publisher"
+	"github.com/aaegamysta/listen-2-max-payne/internal/logging"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter/twittertest"
+	"go.uber.org/zap"
+)
+
+type fakePublisher struct {
+	publisher.Interface
+	status publisher.Status
+}
+
+func (f *fakePublisher) Status() publisher.Status {
+	return f.status
+}
+
+func serve(t *testing.T, h http.Handler, path string) (int, response) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var res response
+	err := json.NewDecoder(rec.Body).Decode(&res)
+	if err != nil {
+		t.Fatalf("failed to decode the response of %s: %v", path, err)
+	}
+	return rec.Code, res
+}
+
+func TestImpl_Healthz(t *testing.T) {
+	pub := &fakePublisher{}
+	h := New(context.Background(), Config{}, zap.NewNop().Sugar(), nil, &dbtest.Repository{}, pub, nil).Handler()
+	if code, res := serve(t, h, "/healthz"); code != http.StatusOK || res.Checks["publisher"].Status != statusOK {
+		t.Fatalf("expected to be alive before publishing starts, got %d %+v", code, res)
+	}
+	pub.status = publisher.Status{TickPeriod: time.Minute, LastTickAt: time.Now().Add(-10 * time.Minute)}
+	if code, res := serve(t, h, "/healthz"); code != http.StatusServiceUnavailable || res.Status != statusFailing {
+		t.Fatalf("expected a stuck publishing loop to fail, got %d %+v", code, res)
+	}
+}
+
+func TestImpl_Readyz(t *testing.T) {
+	server := twittertest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	logger := zap.NewNop().Sugar()
+	repo := &dbtest.Repository{Stats: db.Stats{ActiveExcerpts: 12}}
+	client := twitter.New(ctx, server.Config(), logger, nil, nil)
+	h := New(ctx, Config{}, logger, logging.NewRedactor("hunter2"), repo, &fakePublisher{}, client).Handler()
+
+	code, res := serve(t, h, "/readyz")
+	if code != http.StatusOK || res.Status != statusOK || len(res.Checks) != 3 {
+		t.Fatalf("expected to be ready, got %d %+v", code, res)
+	}
+	// the credentials verified above are reused so the injected failure is not seen
+	server.FailNext(twittertest.ErrUnauthorized)
+	repo.Stats = db.Stats{InactiveExcerpts: 12}
+	repo.PingErr = fmt.Errorf("%w: password hunter2 was rejected", db.ErrDatabaseUnavailable)
+	code, res = serve(t, h, "/readyz")
+	if code != http.StatusServiceUnavailable || res.Checks["twitter"].Status != statusOK {
+		t.Fatalf("expected only the database and the corpus to fail, got %d %+v", code, res)
+	}
+	for _, name := range []string{"database", "corpus"} {
+		if res.Checks[name].Status != statusFailing || res.Checks[name].Error == "" {
+			t.Fatalf("expected the %s check to fail, got %+v", name, res.Checks[name])
+		}
+	}
+	if strings.Contains(res.Checks["database"].Error, "hunter2") {
+		t.Fatalf("expected the password to be redacted, got %s", res.Checks["database"].Error)
+	}
+}
@@ -1,5 +1,54 @@
 package parser
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
 type Config struct {
-	BatchInsertChunkSize int `yaml:"batchInsertChunkSize"`
+	// CorpusPath is the excerpts file or the directory of excerpts files the bot imports and watches
+	CorpusPath           string `yaml:"corpusPath"`
+	BatchInsertChunkSize int    `yaml:"batchInsertChunkSize"`
+	// Format forces the decoder instead of selecting it by file extension, one of json, yaml, csv, ndjson or markdown
+	Format string `yaml:"format"`
+	// CSVColumns maps the series, part, chapter and excerpt fields to the CSV header naming them when it differs
+	CSVColumns map[string]string `yaml:"csvColumns"`
+	// CataloguePath is the YAML catalogue of parts, chapters and characters excerpts are validated against, it is
+	// saved to the database on startup since imports resolve excerpts against the saved one
+	CataloguePath string `yaml:"cataloguePath"`
+	// MaxExcerptLength rejects longer excerpts during validation, excerpts longer than a tweet can only be posted as
+	// threads
+	MaxExcerptLength int `yaml:"maxExcerptLength"`
+	// WatchInterval is how often the corpus is checked for changes to sync, watching is disabled when it is zero
+	WatchInterval time.Duration `yaml:"watchInterval"`
+	// ErrorPolicy is one of fail-fast, skip or quarantine, imports fail fast when it is empty
+	ErrorPolicy ErrorPolicy `yaml:"errorPolicy"`
+}
+
+// Validate reports every missing or invalid field at once
+func (c Config) Validate() error {
+	var errs []error
+	if c.CorpusPath == "" {
+		errs = append(errs, errors.New("corpusPath is required"))
+	}
+	if c.BatchInsertChunkSize <= 0 {
+		errs = append(errs, fmt.Errorf("batchInsertChunkSize %d must be positive", c.BatchInsertChunkSize))
+	}
+	if c.Format != "" {
+		_, err := FormatByName(c.Format)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("format: %w", err))
+		}
+	}
+	if c.MaxExcerptLength < 0 {
+		errs = append(errs, fmt.Errorf("maxExcerptLength %d must not be negative", c.MaxExcerptLength))
+	}
+	if c.WatchInterval < 0 {
+		errs = append(errs, fmt.Errorf("watchInterval %s must not be negative", c.WatchInterval))
+	}
+	if !c.ErrorPolicy.Valid() {
+		errs = append(errs, fmt.Errorf("errorPolicy %q is not one of %s, %s or %s", c.ErrorPolicy, FailFast, Skip, Quarantine))
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+var csvFields = []string{"series", "part", "chapter", "excerpt"}
+
+// decodeCSV reads a CSV file with a header row, columns are matched to fields by name unless
+// Config.CSVColumns maps the field to another header. The defaults are read from the optional "# " comment lines
+// before the header, written as YAML like "# series: 1", the columns of the defaulted fields can then be left out.
+func decodeCSV(ctx context.Context, cfg Config, reader io.Reader, results chan<- Result) error {
+	buffered := bufio.NewReader(reader)
+	defaults, skipped, err := decodeCSVDefaults(buffered)
+	if err != nil {
+		return err
+	}
+	r := csv.NewReader(buffered)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("something wrong happened while reading csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for index, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	indexes := make(map[string]int, len(csvFields))
+	for _, field := range csvFields {
+		name := field
+		if mapped, ok := cfg.CSVColumns[field]; ok {
+			name = mapped
+		}
+		index, ok := columns[strings.ToLower(name)]
+		if !ok && defaults.declares(field) {
+			index = -1
+		} else if !ok {
+			return &PositionError{
+				Position: Position{Line: skipped + 1, Column: 1},
+				Err:      fmt.Errorf("csv header has no %q column for the %s field", name, field),
+			}
+		}
+		indexes[field] = index
+	}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			var parseError *csv.ParseError
+			if !errors.As(err, &parseError) {
+				return fmt.Errorf("something wrong happened while reading csv: %w", err)
+			}
+			position := Position{Line: skipped + parseError.Line, Column: parseError.Column}
+			err = send(ctx, results, Result{
+				Position: position,
+				Error:    &PositionError{Position: position, Err: err},
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		line, column := r.FieldPos(0)
+		position := Position{Line: skipped + line, Column: column}
+		e, err := csvExcerpt(record, indexes)
+		if err != nil {
+			err = &PositionError{Position: position, Err: err}
+		}
+		defaults.apply(&e)
+		err = send(ctx, results, Result{
+			Excerpt:  e,
+			Position: position,
+			Error:    err,
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func csvExcerpt(record []string, indexes map[string]int) (db.Excerpt, error) {
+	value := func(field string) string {
+		if indexes[field] >= 0 && indexes[field] < len(record) {
+			return record[indexes[field]]
+		}
+		return ""
+	}
+	var e db.Excerpt
+	// an empty series is left to the defaults
+	if strings.TrimSpace(value("series")) != "" {
+		series, err := db.ParseSeries(value("series"))
+		if err != nil {
+			return e, err
+		}
+		e.Series = series
+	}
+	e.Part = value("part")
+	e.Chapter = value("chapter")
+	e.Excerpt = value("excerpt")
+	return e, nil
+}
+
+// decodeCSVDefaults reads the comment lines before the header as the YAML defaults of the file, it returns them
+// with the number of lines read
+func decodeCSVDefaults(reader *bufio.Reader) (Defaults, int, error) {
+	var (
+		defaults Defaults
+		text     strings.Builder
+		lines    int
+	)
+	for {
+		next, err := reader.Peek(1)
+		if err != nil || next[0] != '#' {
+			break
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return Defaults{}, 0, fmt.Errorf("something wrong happened while reading csv defaults: %w", err)
+		}
+		lines++
+		line = strings.TrimPrefix(strings.TrimPrefix(line, "#"), " ")
+		text.WriteString(strings.TrimRight(line, "\r\n"))
+		text.WriteByte('\n')
+	}
+	if lines == 0 {
+		return defaults, 0, nil
+	}
+	err := yaml.Unmarshal([]byte(text.String()), &defaults)
+	if err != nil {
+		return Defaults{}, 0, &PositionError{
+			Position: Position{Line: 1, Column: 1},
+			Err:      fmt.Errorf("something wrong happened while decoding the csv defaults: %w", err),
+		}
+	}
+	return defaults, lines, nil
+}
@@ -0,0 +1,36 @@
+package parser
+
+import "github.com/aaegamysta/listen-2-max-payne/internal/db"
+
+// Defaults are the series, part and chapter a file declares for its excerpts that do not set their own
+type Defaults struct {
+	Series  db.Series `json:"series" yaml:"series"`
+	Part    string    `json:"part" yaml:"part"`
+	Chapter string    `json:"chapter" yaml:"chapter"`
+}
+
+func (d Defaults) apply(e *db.Excerpt) {
+	if e.Series == db.Unspecified {
+		e.Series = d.Series
+	}
+	if e.Part == "" {
+		e.Part = d.Part
+	}
+	if e.Chapter == "" {
+		e.Chapter = d.Chapter
+	}
+}
+
+// declares reports whether the defaults set the field, one of series, part or chapter
+func (d Defaults) declares(field string) bool {
+	switch field {
+	case "series":
+		return d.Series != db.Unspecified
+	case "part":
+		return d.Part != ""
+	case "chapter":
+		return d.Chapter != ""
+	default:
+		return false
+	}
+}
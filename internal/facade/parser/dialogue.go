@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+var dialogueMarkers = map[rune]int{
+	'-': db.FirstSpeaker,
+	'=': db.SecondSpeaker,
+}
+
+// ParseDialogue turns the corpus dialogue markers into lines, the text is parsed line by line. A line starting
+// with "-" or "=" is dialogue and every following marker preceded by a space starts a new spoken line, "-" is only
+// a marker when it is directly followed by the spoken text so dashes used as punctuation are kept. Any other line
+// is narration, not attributed to a speaker.
+func ParseDialogue(text string) []db.DialogueLine {
+	lines := make([]db.DialogueLine, 0)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		runes := []rune(line)
+		switch {
+		case len(runes) == 0:
+			continue
+		case isMarker(runes, 0):
+			lines = append(lines, parseSpeech(runes)...)
+		default:
+			lines = append(lines, db.DialogueLine{Kind: db.Narration, Text: line})
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return lines
+}
+
+// parseSpeech splits a line starting with a marker into the lines spoken by the speakers of its markers
+func parseSpeech(runes []rune) []db.DialogueLine {
+	lines := make([]db.DialogueLine, 0)
+	start := 0
+	for i := 1; i <= len(runes); i++ {
+		if i < len(runes) && !(unicode.IsSpace(runes[i-1]) && isMarker(runes, i)) {
+			continue
+		}
+		spoken := strings.TrimSpace(string(runes[start+1 : i]))
+		if spoken != "" {
+			lines = append(lines, db.DialogueLine{Kind: db.Speech, Speaker: dialogueMarkers[runes[start]], Text: spoken})
+		}
+		start = i
+	}
+	return lines
+}
+
+func isMarker(runes []rune, i int) bool {
+	switch runes[i] {
+	case '=':
+		return true
+	case '-':
+		return i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != '-'
+	default:
+		return false
+	}
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+func TestParseDialogue(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []db.DialogueLine
+	}{
+		{
+			text:     "They were all dead. The final gunshot was an exclamation mark - to everything.",
+			expected: []db.DialogueLine{{Kind: db.Narration, Text: "They were all dead. The final gunshot was an exclamation mark - to everything."}},
+		},
+		{
+			text: "-See? My last smoke. It's bad for the baby. =That's you, Max. A regular boy scout.",
+			expected: []db.DialogueLine{
+				{Kind: db.Speech, Speaker: db.FirstSpeaker, Text: "See? My last smoke. It's bad for the baby."},
+				{Kind: db.Speech, Speaker: db.SecondSpeaker, Text: "That's you, Max. A regular boy scout."},
+			},
+		},
+		{
+			text: "-Come on. Don't be like that - have a cigar. = I don't smoke. -Unfair!!",
+			expected: []db.DialogueLine{
+				{Kind: db.Speech, Speaker: db.FirstSpeaker, Text: "Come on. Don't be like that - have a cigar."},
+				{Kind: db.Speech, Speaker: db.SecondSpeaker, Text: "I don't smoke."},
+				{Kind: db.Speech, Speaker: db.FirstSpeaker, Text: "Unfair!!"},
+			},
+		},
+		{
+			text: "The phone rang.\n-Max? It's Alex.\n\nHe sounded scared. =What's wrong?",
+			expected: []db.DialogueLine{
+				{Kind: db.Narration, Text: "The phone rang."},
+				{Kind: db.Speech, Speaker: db.FirstSpeaker, Text: "Max? It's Alex."},
+				{Kind: db.Narration, Text: "He sounded scared. =What's wrong?"},
+			},
+		},
+		{text: " \n ", expected: nil},
+	}
+	for _, tt := range tests {
+		if lines := ParseDialogue(tt.text); !reflect.DeepEqual(lines, tt.expected) {
+			t.Fatalf("expected %+v, got %+v", tt.expected, lines)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DecodeFunc streams every excerpt of reader, or the error found while decoding it, to results.
+// The returned error is for failures that prevent decoding the rest of the source, including ctx being done.
+type DecodeFunc func(ctx context.Context, cfg Config, reader io.Reader, results chan<- Result) error
+
+type Format struct {
+	Name       string
+	Extensions []string
+	Decode     DecodeFunc
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]Format{
+		"json":     {Name: "json", Extensions: []string{".json"}, Decode: decodeJSON},
+		"yaml":     {Name: "yaml", Extensions: []string{".yaml", ".yml"}, Decode: decodeYAML},
+		"csv":      {Name: "csv", Extensions: []string{".csv"}, Decode: decodeCSV},
+		"ndjson":   {Name: "ndjson", Extensions: []string{".ndjson", ".jsonl"}, Decode: decodeNDJSON},
+		"markdown": {Name: "markdown", Extensions: []string{".md", ".markdown"}, Decode: decodeMarkdown},
+	}
+)
+
+// RegisterFormat adds a format or replaces the one registered under the same name
+func RegisterFormat(f Format) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[f.Name] = f
+}
+
+func FormatByName(name string) (Format, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	f, ok := formats[strings.ToLower(name)]
+	if !ok {
+		return Format{}, fmt.Errorf("unknown excerpts format %q, known formats are %s", name, strings.Join(formatNames(), ", "))
+	}
+	return f, nil
+}
+
+func FormatByExtension(path string) (Format, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	for _, f := range formats {
+		for _, e := range f.Extensions {
+			if e == ext {
+				return f, nil
+			}
+		}
+	}
+	return Format{}, fmt.Errorf("no excerpts format is registered for the %q extension of %s", ext, path)
+}
+
+// send delivers result to the consumer unless ctx is done first, decoders return the error to stop decoding
+func send(ctx context.Context, results chan<- Result, result Result) error {
+	select {
+	case results <- result:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// selectFormat prefers the explicitly configured format over the one matching the extension of path
+func selectFormat(explicit string, path string) (Format, error) {
+	if explicit != "" {
+		return FormatByName(explicit)
+	}
+	return FormatByExtension(path)
+}
+
+func formatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+func Test_decodeFormats(t *testing.T) {
+	expected := []db.Excerpt{
+		{
+			Series: 1, Part: "Part I: The American Dream", Chapter: "Roscoe Street Station",
+			Excerpt: "The subway was a perfect place for a crime.",
+		},
+		{
+			Series: 1, Part: "Part I: The American Dream", Chapter: "Playing It Bogart",
+			Excerpt: "-See? My last smoke. =That's you, Max. A regular boy scout.",
+		},
+	}
+	cfg := Config{
+		CSVColumns: map[string]string{"series": "Game", "excerpt": "Text"},
+	}
+	for _, path := range []string{
+		"./testdata/excerpts.yaml",
+		"./testdata/excerpts.csv",
+		"./testdata/excerpts.ndjson",
+		"./testdata/excerpts.md",
+	} {
+		t.Run(path, func(t *testing.T) {
+			format, err := FormatByExtension(path)
+			if err != nil {
+				t.Fatalf("failed to select format: %v", err)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("failed to open test file: %v", err)
+			}
+			defer f.Close()
+			results := make(chan Result, 10)
+			err = format.Decode(context.Background(), cfg, f, results)
+			close(results)
+			if err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			excerpts := make([]db.Excerpt, 0)
+			for result := range results {
+				if result.Error != nil {
+					t.Fatalf("failed to decode excerpt: %v", result.Error)
+				}
+				excerpts = append(excerpts, result.Excerpt)
+			}
+			if !reflect.DeepEqual(excerpts, expected) {
+				t.Fatalf("expected %+v, got %+v", expected, excerpts)
+			}
+		})
+	}
+}
+
+func Test_decodeJSONUnexpectedShape(t *testing.T) {
+	format, err := FormatByName("json")
+	if err != nil {
+		t.Fatalf("failed to find json format: %v", err)
+	}
+	results := make(chan Result, 10)
+	err = format.Decode(context.Background(), Config{}, strings.NewReader(`[{"series": 1}]`), results)
+	if err == nil {
+		t.Fatalf("expected an error instead of a panic for a document that is not an object")
+	}
+}
+
+func Test_decodeMarkdownFrontMatterError(t *testing.T) {
+	format, err := FormatByName("markdown")
+	if err != nil {
+		t.Fatalf("failed to find markdown format: %v", err)
+	}
+	markdown := "---\nseries: 1\npart: prologue\nchapter: [prologue]\n---\n> They were all dead.\n"
+	err = format.Decode(context.Background(), Config{}, strings.NewReader(markdown), make(chan Result, 10))
+	var positionError *PositionError
+	if !errors.As(err, &positionError) || positionError.Position != (Position{Line: 4, Column: 10}) {
+		t.Fatalf("expected the error to be positioned at the chapter, got %v", err)
+	}
+}
+
+func Test_decodeNDJSONLateDefaults(t *testing.T) {
+	format, err := FormatByName("ndjson")
+	if err != nil {
+		t.Fatalf("failed to find ndjson format: %v", err)
+	}
+	ndjson := `{"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "They were all dead."}` + "\n" +
+		`{"defaults": {"series": 1}}` + "\n"
+	err = format.Decode(context.Background(), Config{}, strings.NewReader(ndjson), make(chan Result, 10))
+	var positionError *PositionError
+	if !errors.As(err, &positionError) || positionError.Position.Line != 2 {
+		t.Fatalf("expected defaults after the excerpts to be rejected on line 2, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+// decodeJSON streams the excerpts array of a {"defaults": {...}, "excerpts": [...]} document, the optional
+// defaults must come before the excerpts and other top level keys are skipped
+func decodeJSON(ctx context.Context, _ Config, reader io.Reader, results chan<- Result) error {
+	positions := newPositionReader(reader)
+	decoder := json.NewDecoder(positions)
+	err := expectDelim(decoder, positions, '{')
+	if err != nil {
+		return err
+	}
+	var (
+		defaults     Defaults
+		seenExcerpts bool
+	)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return locateJSONError(positions, decoder.InputOffset(), fmt.Errorf("something wrong happened while reading a key: %w", err))
+		}
+		if key, _ := token.(string); key == "defaults" {
+			if seenExcerpts {
+				return &PositionError{
+					Position: positions.position(decoder.InputOffset() - int64(len(`"defaults"`))),
+					Err:      errors.New("defaults must come before the excerpts"),
+				}
+			}
+			err = decoder.Decode(&defaults)
+			if err != nil {
+				return locateJSONError(positions, decoder.InputOffset(),
+					fmt.Errorf("something wrong happened while decoding defaults: %w", err))
+			}
+			continue
+		}
+		if key, _ := token.(string); key != "excerpts" {
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+			if err != nil {
+				return locateJSONError(positions, decoder.InputOffset(),
+					fmt.Errorf("something wrong happened while skipping key %v: %w", token, err))
+			}
+			continue
+		}
+		seenExcerpts = true
+		err = expectDelim(decoder, positions, '[')
+		if err != nil {
+			return err
+		}
+		for decoder.More() {
+			// the value is decoded raw first so its start is known without keeping what was read
+			var raw json.RawMessage
+			var e db.Excerpt
+			err := decoder.Decode(&raw)
+			startOffset := decoder.InputOffset() - int64(len(raw))
+			if err != nil {
+				err = locateJSONError(positions, decoder.InputOffset(), err)
+			} else {
+				err = json.Unmarshal(raw, &e)
+				var typeError *json.UnmarshalTypeError
+				if errors.As(err, &typeError) {
+					// the offset of type errors is relative to the start of the decoded value
+					err = &PositionError{Position: positions.position(startOffset + typeError.Offset - 1), Err: err}
+				} else if err != nil {
+					err = locateJSONError(positions, decoder.InputOffset(), err)
+				}
+			}
+			defaults.apply(&e)
+			sendErr := send(ctx, results, Result{
+				Excerpt:  e,
+				Position: positions.position(startOffset),
+				Error:    err,
+			})
+			if sendErr != nil {
+				return sendErr
+			}
+			var syntaxError *json.SyntaxError
+			if errors.As(err, &syntaxError) {
+				// the decoder cannot recover from malformed input
+				return nil
+			}
+		}
+		err = expectDelim(decoder, positions, ']')
+		if err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, positions, '}')
+}
+
+func expectDelim(decoder *json.Decoder, positions *positionReader, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return locateJSONError(positions, decoder.InputOffset(), fmt.Errorf("something wrong happened while reading %q: %w", delim, err))
+	}
+	if token != delim {
+		return &PositionError{
+			Position: positions.position(decoder.InputOffset() - 1),
+			Err:      fmt.Errorf("expected %q but found %v", delim, token),
+		}
+	}
+	return nil
+}
+
+// locateJSONError positions err at the offset reported by the json package, or at fallback when it has none
+func locateJSONError(positions *positionReader, fallback int64, err error) error {
+	offset := fallback
+	var syntaxError *json.SyntaxError
+	if errors.As(err, &syntaxError) {
+		// the offset is the number of bytes read when the error occurred, including the offending one
+		offset = syntaxError.Offset - 1
+	}
+	return &PositionError{Position: positions.position(offset), Err: err}
+}
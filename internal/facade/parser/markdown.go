@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+// maxMarkdownLineSize bounds the lines of markdown files, a blockquote line holds a whole paragraph of an excerpt
+const maxMarkdownLineSize = 1024 * 1024
+
+// decodeMarkdown reads "# " headings as parts, "## " headings as chapters and every blockquote paragraph
+// as an excerpt of the current part and chapter, the defaults are read from the optional YAML front matter
+// delimited by --- lines at the top of the file
+func decodeMarkdown(ctx context.Context, _ Config, reader io.Reader, results chan<- Result) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxMarkdownLineSize)
+	var (
+		current   db.Excerpt
+		quote     []string
+		start     Position
+		line      int
+		inFront   bool
+		frontText strings.Builder
+	)
+	flush := func() error {
+		if len(quote) == 0 {
+			return nil
+		}
+		e := current
+		e.Excerpt = strings.Join(quote, " ")
+		quote = nil
+		return send(ctx, results, Result{Excerpt: e, Position: start})
+	}
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case line == 1 && trimmed == "---":
+			inFront = true
+		case inFront && trimmed == "---":
+			inFront = false
+			front, err := decodeFrontMatter(frontText.String())
+			if err != nil {
+				return err
+			}
+			front.apply(&current)
+		case inFront:
+			frontText.WriteString(text)
+			frontText.WriteByte('\n')
+		case strings.HasPrefix(trimmed, "## "):
+			err := flush()
+			if err != nil {
+				return err
+			}
+			current.Chapter = strings.TrimSpace(strings.TrimPrefix(trimmed, "## "))
+		case strings.HasPrefix(trimmed, "# "):
+			err := flush()
+			if err != nil {
+				return err
+			}
+			current.Part = strings.TrimSpace(strings.TrimPrefix(trimmed, "# "))
+			current.Chapter = ""
+		case strings.HasPrefix(trimmed, ">"):
+			content := strings.TrimSpace(strings.TrimPrefix(trimmed, ">"))
+			if content == "" {
+				// an empty quoted line separates two excerpts inside the same blockquote
+				err := flush()
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if len(quote) == 0 {
+				start = Position{Line: line, Column: strings.Index(text, content) + 1}
+			}
+			quote = append(quote, content)
+		default:
+			err := flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return fmt.Errorf("something wrong happened while reading line %d: %w", line+1, err)
+	}
+	if inFront {
+		return fmt.Errorf("the markdown front matter is never closed")
+	}
+	return flush()
+}
+
+// decodeFrontMatter decodes the defaults of the front matter starting on the second line of the file, errors are
+// positioned at the value that could not be decoded
+func decodeFrontMatter(text string) (Defaults, error) {
+	var defaults Defaults
+	var document yaml.Node
+	err := yaml.Unmarshal([]byte(text), &document)
+	if err != nil {
+		// syntax errors only tell their line in their message so they are positioned at the front matter
+		return Defaults{}, frontMatterError(&yaml.Node{Line: 1, Column: 1}, err)
+	}
+	if len(document.Content) == 0 {
+		return defaults, nil
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return Defaults{}, frontMatterError(root, root.Decode(&defaults))
+	}
+	// the keys are decoded one at a time to know which value fails
+	for index := 0; index+1 < len(root.Content); index += 2 {
+		pair := &yaml.Node{Kind: yaml.MappingNode, Tag: root.Tag, Content: root.Content[index : index+2]}
+		err = pair.Decode(&defaults)
+		if err != nil {
+			return Defaults{}, frontMatterError(root.Content[index+1], err)
+		}
+	}
+	return defaults, nil
+}
+
+// frontMatterError positions err at node, the lines of the front matter are offset by the opening --- line
+func frontMatterError(node *yaml.Node, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PositionError{
+		Position: Position{Line: node.Line + 1, Column: node.Column},
+		Err:      fmt.Errorf("something wrong happened while decoding the markdown front matter: %w", err),
+	}
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+const maxNDJSONLineSize = 1024 * 1024
+
+// decodeNDJSON reads one excerpt object per line, blank lines are ignored. The first line can instead be a
+// {"defaults": {...}} record declaring the defaults of the file.
+func decodeNDJSON(ctx context.Context, _ Config, reader io.Reader, results chan<- Result) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxNDJSONLineSize)
+	var (
+		defaults Defaults
+		line     int
+		records  int
+	)
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		records++
+		position := Position{Line: line, Column: 1}
+		var metadata struct {
+			Defaults json.RawMessage `json:"defaults"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &metadata) == nil && metadata.Defaults != nil {
+			if records > 1 {
+				return &PositionError{Position: position, Err: errors.New("defaults must come before the excerpts")}
+			}
+			err := json.Unmarshal(metadata.Defaults, &defaults)
+			if err != nil {
+				return &PositionError{Position: position, Err: fmt.Errorf("something wrong happened while decoding defaults: %w", err)}
+			}
+			continue
+		}
+		var e db.Excerpt
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			errorPosition := position
+			var syntaxError *json.SyntaxError
+			if errors.As(err, &syntaxError) {
+				errorPosition.Column = int(syntaxError.Offset)
+			}
+			err = &PositionError{Position: errorPosition, Err: err}
+		}
+		defaults.apply(&e)
+		err = send(ctx, results, Result{
+			Excerpt:  e,
+			Position: position,
+			Error:    err,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return &PositionError{
+			Position: Position{Line: line + 1, Column: 1},
+			Err:      fmt.Errorf("something wrong happened while reading line: %w", err),
+		}
+	}
+	return nil
+}
@@ -2,104 +2,280 @@ package parser
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/metrics"
 	"go.uber.org/zap"
 )
 
 type Interface interface {
-	ParseAndSaveExcerpts(ctx context.Context, reader io.Reader) error
+	// ParseAndSaveExcerpts decodes reader with the configured format, json when none is configured, and saves its
+	// excerpts in a single transaction. Cancelling ctx stops decoding and rolls the import back, progress is optional.
+	ParseAndSaveExcerpts(ctx context.Context, reader io.Reader, progress ProgressFunc) error
+	// ParseAndSaveExcerptsFromFile is ParseAndSaveExcerpts for the file, decoded with the configured format or the
+	// one matching its extension, or the directory of files, see corpusFiles
+	ParseAndSaveExcerptsFromFile(ctx context.Context, path string, progress ProgressFunc) error
+	// SyncExcerptsFromFile validates the file or directory and makes its excerpts the active corpus, nothing changes
+	// when it is invalid
+	SyncExcerptsFromFile(ctx context.Context, path string) (db.SyncSummary, error)
+	// StartWatching syncs the file or directory at path every time it changes, starting with a sync of its current content
+	StartWatching(ctx context.Context, path string)
+	// StopWatching waits for the watcher to return once the context it was started with is done
+	StopWatching(ctx context.Context) error
 }
 
 type Result struct {
 	Excerpt db.Excerpt
-	Error   error
+	// File is the file of the corpus the excerpt was read from, empty when it was not read from a file
+	File string
+	// Position is where the excerpt starts in its source
+	Position Position
+	Error    error
 }
 
 type impl struct {
 	logger               *zap.SugaredLogger
+	cfg                  Config
 	repository           db.Interface
 	batchInsertChunkSize int
+	// watching is closed once the watcher returns, it is nil when the corpus is not watched
+	watching chan struct{}
 }
 
-func (i *impl) ParseAndSaveExcerpts(ctx context.Context, reader io.Reader) error {
-	excerptResultsStream := i.parse(ctx, reader, i.batchInsertChunkSize)
-	err := i.save(ctx, i.batchInsertChunkSize, excerptResultsStream)
+func (i *impl) ParseAndSaveExcerpts(ctx context.Context, reader io.Reader, progress ProgressFunc) error {
+	format, err := selectFormat(i.cfg.Format, ".json")
+	if err != nil {
+		return err
+	}
+	return i.parseAndSave(ctx, []source{readerSource("", reader, format)}, progress)
+}
+
+func (i *impl) ParseAndSaveExcerptsFromFile(ctx context.Context, path string, progress ProgressFunc) error {
+	sources, err := fileSources(i.cfg, path)
+	if err != nil {
+		return err
+	}
+	return i.parseAndSave(ctx, sources, progress)
+}
+
+func (i *impl) parseAndSave(ctx context.Context, sources []source, progress ProgressFunc) error {
+	// an unknown policy would otherwise only show once an excerpt is rejected
+	if !i.cfg.ErrorPolicy.Valid() {
+		return fmt.Errorf("errorPolicy %q is not one of %s, %s or %s", i.cfg.ErrorPolicy, FailFast, Skip, Quarantine)
+	}
+	catalogue, err := i.repository.GetCatalogue(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load the catalogue excerpts are resolved against: %w", err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	excerptResultsStream := i.parseSources(ctx, sources, i.batchInsertChunkSize)
+	err = i.save(ctx, i.batchInsertChunkSize, catalogue, excerptResultsStream, progress)
+	// save returns before consuming every result when the import fails, cancelling stops the decoders and
+	// draining waits for them to return
+	cancel()
+	for range excerptResultsStream {
+	}
 	if err != nil {
 		return err
 	}
 	return io.EOF
 }
 
-func (i *impl) parse(_ context.Context, reader io.Reader, chunks int) <-chan Result {
+func (i *impl) parse(ctx context.Context, reader io.Reader, format Format, chunks int) <-chan Result {
+	return i.parseSources(ctx, []source{readerSource("", reader, format)}, chunks)
+}
+
+// parseSources decodes the sources one after the other, every result and error is attributed to its source.
+// The stream is closed once every source is decoded or as soon as ctx is done.
+func (i *impl) parseSources(ctx context.Context, sources []source, chunks int) <-chan Result {
 	excerptsResultsStream := make(chan Result, chunks)
-	decoder := json.NewDecoder(reader)
 	go func() {
 		defer close(excerptsResultsStream)
-		// Read the opening brace of the JSON object
-		_, err := decoder.Token()
-		if err != nil {
-			i.logger.Panicf("something wrong happened while reading opening brace:", err)
-			return
-		}
-
-		// Read the "excerpts" key
-		_, err = decoder.Token()
-		if err != nil {
-			i.logger.Panicf("something wrong happened while parsing excerpts key:", err)
-			return
-		}
-
-		// Read the opening bracket of the excerpts array
-		_, err = decoder.Token()
-		if err != nil {
-			i.logger.Panicf("something wrong happened while parsing opening bracket:", err)
-			return
-		}
-		for decoder.More() {
-			var e db.Excerpt
-			err := decoder.Decode(&e)
-			excerptsResultsStream <- Result{
-				Excerpt: e,
-				Error:   err,
+		for _, s := range sources {
+			err := i.decodeSource(ctx, s, excerptsResultsStream)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				// the next sources are still decoded for the error policies that do not abort the import
+				err = send(ctx, excerptsResultsStream, Result{File: s.name, Error: err})
+				if err != nil {
+					return
+				}
 			}
 		}
-		i.logger.Infof("finishing parsing all excerpts")
+		i.logger.Info("finishing parsing all excerpts")
 	}()
 	return excerptsResultsStream
 }
 
-func (i *impl) save(ctx context.Context, chunks int, excerptsResults <-chan Result) error {
+func (i *impl) decodeSource(ctx context.Context, s source, results chan<- Result) error {
+	reader, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	decoded := make(chan Result)
+	decodeErrors := make(chan error, 1)
+	go func() {
+		defer close(decoded)
+		decodeErrors <- s.format.Decode(ctx, i.cfg, reader, decoded)
+	}()
+	for result := range decoded {
+		result.File = s.name
+		result.Error = attribute(s.name, result.Error)
+		if send(ctx, results, result) != nil {
+			// the decoder stops on its own since it sends on the same context
+			break
+		}
+	}
+	err = <-decodeErrors
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return attribute(s.name, fmt.Errorf("failed to decode %s excerpts: %w", s.format.Name, err))
+	}
+	return nil
+}
+
+// attribute records the file a position error was found in
+func attribute(file string, err error) error {
+	var positionError *PositionError
+	if errors.As(err, &positionError) && positionError.File == "" {
+		positionError.File = file
+	}
+	return err
+}
+
+// save prepares every excerpt and inserts them by chunks in a single import, the excerpts that cannot be prepared
+// are handled according to the error policy
+func (i *impl) save(ctx context.Context,
+	chunks int,
+	catalogue db.Catalogue,
+	excerptsResults <-chan Result,
+	progress ProgressFunc,
+) error {
+	imp, err := i.repository.BeginImport(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// the rollback has to reach the database even when the import stops because ctx is done
+		_ = imp.Rollback(context.WithoutCancel(ctx))
+	}()
+	var p Progress
+	defer func() {
+		observeImport(p)
+	}()
+	report := func() {
+		if progress != nil {
+			progress(p)
+		}
+	}
 	excerpts := make([]db.Excerpt, 0)
-	counter := 0
+	rejected := make([]error, 0)
 	for result := range excerptsResults {
-		if counter == chunks {
-			_, err := i.repository.BatchInsertExcerpts(ctx, excerpts)
+		p.Parsed++
+		err := result.Error
+		if err == nil {
+			err = prepare(catalogue, &result)
+		}
+		if err != nil {
+			rejectErr := i.reject(ctx, imp, result, err)
+			if rejectErr != nil {
+				return rejectErr
+			}
+			rejected = append(rejected, err)
+			p.Rejected++
+			continue
+		}
+		excerpts = append(excerpts, result.Excerpt)
+		if len(excerpts) == chunks {
+			err = imp.InsertExcerpts(ctx, excerpts)
 			if err != nil {
 				return err
 			}
+			p.Saved += len(excerpts)
+			report()
 			excerpts = make([]db.Excerpt, 0)
-			counter = 0
 		}
-		if result.Error != nil {
-			return result.Error
+	}
+	// the stream is also closed when ctx is done, which must not commit a partial import
+	if ctx.Err() != nil {
+		return fmt.Errorf("the import was cancelled: %w", ctx.Err())
+	}
+	if len(excerpts) > 0 {
+		err = imp.InsertExcerpts(ctx, excerpts)
+		if err != nil {
+			return err
 		}
-		excerpts = append(excerpts, result.Excerpt)
-		counter++
+		p.Saved += len(excerpts)
 	}
-	_, err := i.repository.BatchInsertExcerpts(ctx, excerpts)
+	err = imp.Commit(ctx)
 	if err != nil {
 		return err
 	}
-	i.logger.Infof("finishing saving all excerpts")
+	p.Done = true
+	report()
+	i.logger.Infow("finishing saving all excerpts", "saved", p.Saved, "rejected", len(rejected))
+	if len(rejected) > 0 {
+		return &RejectedError{Rejected: rejected}
+	}
+	return nil
+}
+
+// observeImport counts the records of an import by outcome, none of them are saved unless it is done
+func observeImport(p Progress) {
+	if !p.Done {
+		metrics.ImportRecords.WithLabelValues("rolled_back").Add(float64(p.Parsed))
+		return
+	}
+	metrics.ImportRecords.WithLabelValues("saved").Add(float64(p.Saved))
+	metrics.ImportRecords.WithLabelValues("rejected").Add(float64(p.Rejected))
+}
+
+// prepare resolves the part, chapter and speakers of the excerpt of result against the catalogue and structures
+// its dialogue
+func prepare(catalogue db.Catalogue, result *Result) error {
+	chapter, err := catalogue.Resolve(result.Excerpt.Series, result.Excerpt.Part, result.Excerpt.Chapter)
+	if err != nil {
+		return &PositionError{Position: result.Position, Err: err}
+	}
+	result.Excerpt.ChapterID = chapter.ID
+	result.Excerpt.Source = result.File
+	err = resolveSpeakers(catalogue, &result.Excerpt)
+	if err != nil {
+		return &PositionError{Position: result.Position, Err: err}
+	}
+	if len(result.Excerpt.Dialogue) == 0 {
+		result.Excerpt.Dialogue = ParseDialogue(result.Excerpt.Excerpt)
+	}
+	return nil
+}
+
+// resolveSpeakers replaces the speakers of the excerpt by the canonical names of the characters they refer to
+func resolveSpeakers(catalogue db.Catalogue, e *db.Excerpt) error {
+	e.SpeakerIDs = make([]int, 0, len(e.Speakers))
+	for index, speaker := range e.Speakers {
+		character, err := catalogue.ResolveCharacter(speaker)
+		if err != nil {
+			return err
+		}
+		e.Speakers[index] = character.Name
+		e.SpeakerIDs = append(e.SpeakerIDs, character.ID)
+	}
 	return nil
 }
 
 func New(_ context.Context, cfg Config, logger *zap.SugaredLogger, repo db.Interface) Interface {
 	return &impl{
 		logger:               logger,
+		cfg:                  cfg,
 		repository:           repo,
 		batchInsertChunkSize: cfg.BatchInsertChunkSize,
 	}
@@ -19,9 +19,13 @@ func Test_parse(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to open test file: %v", err)
 	}
-	p := New(logger.Sugar(), nil)
+	p := New(context.Background(), Config{}, logger.Sugar(), nil)
 	pImpl := p.(*impl)
-	Result := pImpl.parse(context.Background(), f, 10)
+	format, err := FormatByName("json")
+	if err != nil {
+		t.Fatalf("failed to find json format: %v", err)
+	}
+	Result := pImpl.parse(context.Background(), f, format, 10)
 	start := time.Now()
 	log.Printf("started parsing at %s", start)
 	counter := 0
@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+// ErrorPolicy is what an import does with the excerpts that cannot be decoded or resolved against the catalogue
+type ErrorPolicy string
+
+const (
+	// FailFast aborts the import on the first invalid excerpt and rolls back everything it saved
+	FailFast ErrorPolicy = "fail-fast"
+	// Skip leaves invalid excerpts out of the import and reports them
+	Skip ErrorPolicy = "skip"
+	// Quarantine saves invalid excerpts with their error in the rejected_excerpts table and reports them
+	Quarantine ErrorPolicy = "quarantine"
+)
+
+// Valid reports whether the policy is empty, which fails fast, or one of the known policies
+func (p ErrorPolicy) Valid() bool {
+	switch p {
+	case "", FailFast, Skip, Quarantine:
+		return true
+	default:
+		return false
+	}
+}
+
+// RejectedError is returned by an import that completed while leaving out the rejected excerpts
+type RejectedError struct {
+	Rejected []error
+}
+
+func (e *RejectedError) Error() string {
+	messages := make([]string, 0, len(e.Rejected))
+	for _, err := range e.Rejected {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d excerpts were rejected:\n%s", len(e.Rejected), strings.Join(messages, "\n"))
+}
+
+// reject applies the error policy to the excerpt of result that failed with err, the returned error aborts the import
+func (i *impl) reject(ctx context.Context, imp db.Import, result Result, err error) error {
+	switch i.cfg.ErrorPolicy {
+	case Skip:
+		i.logger.Warnw("skipping excerpt", "file", result.File, "error", err)
+		return nil
+	case Quarantine:
+		rejected := db.RejectedExcerpt{
+			Source:  result.File,
+			Excerpt: result.Excerpt,
+			Error:   err.Error(),
+		}
+		position := result.Position
+		var positionError *PositionError
+		if errors.As(err, &positionError) {
+			position = positionError.Position
+			rejected.Error = positionError.Err.Error()
+		}
+		if position != (Position{}) {
+			rejected.Position = position.String()
+		}
+		i.logger.Warnw("quarantining excerpt", "file", result.File, "position", rejected.Position, "error", err)
+		return imp.RejectExcerpt(ctx, rejected)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db/dbtest"
+	"go.uber.org/zap"
+)
+
+type fakeImport struct {
+	inserted   []db.Excerpt
+	rejected   []db.RejectedExcerpt
+	committed  bool
+	rolledBack bool
+}
+
+func (f *fakeImport) InsertExcerpts(_ context.Context, excerpts []db.Excerpt) error {
+	f.inserted = append(f.inserted, excerpts...)
+	return nil
+}
+
+func (f *fakeImport) RejectExcerpt(_ context.Context, rejected db.RejectedExcerpt) error {
+	f.rejected = append(f.rejected, rejected)
+	return nil
+}
+
+func (f *fakeImport) Commit(_ context.Context) error {
+	f.committed = true
+	return nil
+}
+
+func (f *fakeImport) Rollback(_ context.Context) error {
+	f.rolledBack = !f.committed
+	return nil
+}
+
+func TestErrorPolicies(t *testing.T) {
+	corpus := `{"excerpts": [
+  {"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "They were all dead."},
+  {"series": 1, "part": "prologue", "chapter": "prolog", "excerpt": "The final gunshot."},
+  {"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "It was over."}
+]}`
+	tests := []struct {
+		policy    ErrorPolicy
+		inserted  int
+		rejected  int
+		committed bool
+	}{
+		{policy: "", inserted: 1, committed: false},
+		{policy: FailFast, inserted: 1, committed: false},
+		{policy: Skip, inserted: 2, committed: true},
+		{policy: Quarantine, inserted: 2, rejected: 1, committed: true},
+	}
+	for _, tt := range tests {
+		imp := &fakeImport{}
+		repo := &dbtest.Repository{Import: imp, Catalogue: db.Catalogue{Series: []db.CatalogueSeries{{Series: 1, Parts: []db.CataloguePart{
+			{ID: 1, Name: "prologue", Chapters: []db.CatalogueChapter{{ID: 1, Name: "prologue"}}},
+		}}}}}
+		p := New(context.Background(), Config{ErrorPolicy: tt.policy, BatchInsertChunkSize: 1}, zap.NewNop().Sugar(), repo)
+		err := p.ParseAndSaveExcerpts(context.Background(), strings.NewReader(corpus), nil)
+		var rejected *RejectedError
+		if tt.committed && (!errors.As(err, &rejected) || len(rejected.Rejected) != 1) {
+			t.Fatalf("expected one rejected excerpt with the %q policy, got %v", tt.policy, err)
+		}
+		if !tt.committed && (err == nil || errors.As(err, &rejected)) {
+			t.Fatalf("expected the import to fail with the %q policy, got %v", tt.policy, err)
+		}
+		if len(imp.inserted) != tt.inserted || len(imp.rejected) != tt.rejected ||
+			imp.committed != tt.committed || imp.rolledBack == tt.committed {
+			t.Fatalf("unexpected import with the %q policy: %+v", tt.policy, imp)
+		}
+	}
+}
+
+func TestErrorPolicyUnknown(t *testing.T) {
+	if err := (Config{CorpusPath: "excerpts.json", BatchInsertChunkSize: 1, ErrorPolicy: "ignore"}).Validate(); err == nil {
+		t.Fatalf("expected an unknown error policy to be invalid")
+	}
+	imp := &fakeImport{}
+	repo := &dbtest.Repository{Import: imp, Catalogue: testCatalogue()}
+	p := New(context.Background(), Config{ErrorPolicy: "ignore", BatchInsertChunkSize: 1}, zap.NewNop().Sugar(), repo)
+	corpus := `{"excerpts": [{"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "They were all dead."}]}`
+	err := p.ParseAndSaveExcerpts(context.Background(), strings.NewReader(corpus), nil)
+	if err == nil || !strings.Contains(err.Error(), `"ignore"`) || len(imp.inserted) != 0 {
+		t.Fatalf("expected the import to be refused with an unknown error policy, got %v", err)
+	}
+}
+
+func TestErrorPolicyFailFastStopsDecoding(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	imp := &fakeImport{}
+	repo := &dbtest.Repository{Import: imp, Catalogue: testCatalogue()}
+	p := New(context.Background(), Config{ErrorPolicy: FailFast, BatchInsertChunkSize: 10}, zap.NewNop().Sugar(), repo)
+	// the invalid excerpt is followed by a corpus that never ends, only cancelling the decoder stops it
+	corpus := io.MultiReader(strings.NewReader(`{"excerpts": [
+  {"series": 1, "part": "prologue", "chapter": "prolog", "excerpt": "The final gunshot."},`), &endlessExcerpts{})
+	err := p.ParseAndSaveExcerpts(context.Background(), corpus, nil)
+	var rejected *RejectedError
+	if err == nil || errors.As(err, &rejected) || imp.committed {
+		t.Fatalf("expected the import to fail fast, got %v", err)
+	}
+	if runtime.NumGoroutine() > goroutines {
+		t.Fatalf("expected the decoder to be stopped once the import failed, %d goroutines are still running",
+			runtime.NumGoroutine()-goroutines)
+	}
+}
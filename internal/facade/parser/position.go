@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Position is a 1-based line and column in a source, the zero value means the position is unknown
+type Position struct {
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// PositionError is returned by decoders for errors that can be located in the source, File is only known once
+// the parser attributes the error to the file it decodes
+type PositionError struct {
+	File     string
+	Position Position
+	Err      error
+}
+
+func (e *PositionError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%s: %v", e.File, e.Position, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Position, e.Err)
+}
+
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// positionReader keeps the offsets of the line breaks read so byte offsets reported by decoders can be turned
+// into positions
+type positionReader struct {
+	reader   io.Reader
+	read     int64
+	newlines []int64
+}
+
+func newPositionReader(reader io.Reader) *positionReader {
+	return &positionReader{reader: reader}
+}
+
+func (p *positionReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	for index, c := range b[:n] {
+		if c == '\n' {
+			p.newlines = append(p.newlines, p.read+int64(index))
+		}
+	}
+	p.read += int64(n)
+	return n, err
+}
+
+func (p *positionReader) position(offset int64) Position {
+	line := sort.Search(len(p.newlines), func(i int) bool { return p.newlines[i] >= offset })
+	lineStart := int64(0)
+	if line > 0 {
+		lineStart = p.newlines[line-1] + 1
+	}
+	return Position{Line: line + 1, Column: int(offset-lineStart) + 1}
+}
@@ -0,0 +1,12 @@
+package parser
+
+// Progress counts the excerpts of an import so far, saved excerpts only become visible once Done
+type Progress struct {
+	Parsed   int
+	Saved    int
+	Rejected int
+	Done     bool
+}
+
+// ProgressFunc is called after every chunk of excerpts is saved and once more when the import is committed
+type ProgressFunc func(Progress)
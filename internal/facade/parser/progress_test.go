@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db/dbtest"
+	"go.uber.org/zap"
+)
+
+// endlessExcerpts is a JSON corpus that never ends
+type endlessExcerpts struct {
+	next    int
+	pending []byte
+}
+
+func (e *endlessExcerpts) Read(b []byte) (int, error) {
+	if len(e.pending) == 0 {
+		e.next++
+		e.pending = fmt.Appendf(nil, `{"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "excerpt %d"},`, e.next)
+	}
+	n := copy(b, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+// cancellingImport cancels the import once the first chunk is inserted
+type cancellingImport struct {
+	fakeImport
+	cancel context.CancelFunc
+}
+
+func (c *cancellingImport) InsertExcerpts(ctx context.Context, excerpts []db.Excerpt) error {
+	c.cancel()
+	return c.fakeImport.InsertExcerpts(ctx, excerpts)
+}
+
+func testCatalogue() db.Catalogue {
+	return db.Catalogue{Series: []db.CatalogueSeries{{Series: 1, Parts: []db.CataloguePart{
+		{ID: 1, Name: "prologue", Chapters: []db.CatalogueChapter{{ID: 1, Name: "prologue"}}},
+	}}}}
+}
+
+func TestParseAndSaveExcerptsCancellation(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	imp := &cancellingImport{cancel: cancel}
+	repo := &dbtest.Repository{Import: imp, Catalogue: testCatalogue()}
+	p := New(context.Background(), Config{BatchInsertChunkSize: 10}, zap.NewNop().Sugar(), repo)
+	corpus := io.MultiReader(strings.NewReader(`{"excerpts": [`), &endlessExcerpts{})
+	err := p.ParseAndSaveExcerpts(ctx, corpus, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the import to be cancelled, got %v", err)
+	}
+	if imp.committed || !imp.rolledBack {
+		t.Fatalf("expected the cancelled import to be rolled back, got %+v", imp.fakeImport)
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the decoding goroutines to stop, %d are still running", runtime.NumGoroutine()-goroutines)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseAndSaveExcerptsProgress(t *testing.T) {
+	repo := &dbtest.Repository{Import: &fakeImport{}, Catalogue: testCatalogue()}
+	p := New(context.Background(), Config{BatchInsertChunkSize: 2, ErrorPolicy: Skip}, zap.NewNop().Sugar(), repo)
+	corpus := `{"excerpts": [
+  {"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "They were all dead."},
+  {"series": 1, "part": "prologue", "chapter": "prolog", "excerpt": "The final gunshot."},
+  {"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "It was over."},
+  {"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "Back to the night."}
+]}`
+	var reported []Progress
+	err := p.ParseAndSaveExcerpts(context.Background(), strings.NewReader(corpus), func(progress Progress) {
+		reported = append(reported, progress)
+	})
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) {
+		t.Fatalf("expected one rejected excerpt, got %v", err)
+	}
+	expected := []Progress{
+		{Parsed: 3, Saved: 2, Rejected: 1},
+		{Parsed: 4, Saved: 3, Rejected: 1, Done: true},
+	}
+	if !reflect.DeepEqual(reported, expected) {
+		t.Fatalf("expected progress %v, got %v", expected, reported)
+	}
+}
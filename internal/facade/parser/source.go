@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// source is an input of the corpus, name is the file it is read from and is empty for plain readers
+type source struct {
+	name   string
+	format Format
+	open   func() (io.ReadCloser, error)
+}
+
+func readerSource(name string, reader io.Reader, format Format) source {
+	return source{
+		name:   name,
+		format: format,
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(reader), nil
+		},
+	}
+}
+
+// corpusFiles returns path when it is a file, or every file of the directory tree at path with the extension of a
+// registered format in lexical order so files are always merged the same way, hidden files and directories are skipped
+func corpusFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while opening excerpts path: %w", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	files := make([]string, 0)
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file != path && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if _, err := FormatByExtension(file); err == nil {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while walking excerpts directory %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// fileSources opens the corpus files of path lazily, each one decoded with the configured format or the one
+// matching its extension
+func fileSources(cfg Config, path string) ([]source, error) {
+	files, err := corpusFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]source, 0, len(files))
+	for _, file := range files {
+		format, err := selectFormat(cfg.Format, file)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source{
+			name:   file,
+			format: format,
+			open: func() (io.ReadCloser, error) {
+				f, err := os.Open(file)
+				if err != nil {
+					return nil, fmt.Errorf("something wrong happened while opening excerpts file: %w", err)
+				}
+				return f, nil
+			},
+		})
+	}
+	return sources, nil
+}
+
+// snapshotSources reads every source once so they can be decoded several times with the same content
+func snapshotSources(sources []source) ([]source, error) {
+	snapshots := make([]source, 0, len(sources))
+	for _, s := range sources {
+		reader, err := s.open()
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("something wrong happened while reading %s: %w", s.name, err)
+		}
+		snapshots = append(snapshots, source{
+			name:   s.name,
+			format: s.format,
+			open: func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(b)), nil
+			},
+		})
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,56 @@
+package parser
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestParseDirectory(t *testing.T) {
+	p := New(context.Background(), Config{}, zap.NewNop().Sugar(), nil).(*impl)
+	sources, err := fileSources(Config{}, "./testdata/corpus")
+	if err != nil {
+		t.Fatalf("failed to list corpus files: %v", err)
+	}
+	expected := []struct {
+		file, part, chapter string
+	}{
+		{file: "max-payne-1/part-1.yaml", part: "Part I: The American Dream", chapter: "Roscoe Street Station"},
+		{file: "max-payne-1/part-1.yaml", part: "Part I: The American Dream", chapter: "Playing It Bogart"},
+		{file: "max-payne-1/part-2.json", part: "Part II: A Cold Day in Hell", chapter: "Ragna Rock"},
+		{file: "max-payne-1/part-3.csv", part: "Part III: Nothing to Lose", chapter: "An Empire of Evil"},
+		{file: "max-payne-1/part-4.ndjson", part: "Part III: Nothing to Lose", chapter: "Take Me to the Cemetery"},
+		{file: "prologue.md", part: "prologue", chapter: "prologue"},
+	}
+	index := 0
+	for result := range p.parseSources(context.Background(), sources, 10) {
+		if result.Error != nil {
+			t.Fatalf("failed to parse %s: %v", result.File, result.Error)
+		}
+		if index >= len(expected) {
+			t.Fatalf("unexpected excerpt %+v from %s", result.Excerpt, result.File)
+		}
+		e := expected[index]
+		if result.File != filepath.Join("testdata/corpus", e.file) || result.Excerpt.Series != 1 ||
+			result.Excerpt.Part != e.part || result.Excerpt.Chapter != e.chapter {
+			t.Fatalf("expected excerpt %d from %s in %q/%q, got %+v from %s", index, e.file, e.part, e.chapter, result.Excerpt, result.File)
+		}
+		index++
+	}
+	if index != len(expected) {
+		t.Fatalf("expected %d excerpts, got %d", len(expected), index)
+	}
+}
+
+func TestValidateDirectory(t *testing.T) {
+	diagnostics, err := Validate(context.Background(), Config{}, "./testdata/corpus")
+	if err != nil {
+		t.Fatalf("failed to validate corpus: %v", err)
+	}
+	expected := "testdata/corpus/max-payne-1/part-2.json:4:5: error: duplicate excerpt, first seen at testdata/corpus/max-payne-1/part-1.yaml:6:5"
+	if len(diagnostics) != 1 || diagnostics[0].String() != expected {
+		t.Fatalf("expected %q, got %v", expected, diagnostics)
+	}
+}
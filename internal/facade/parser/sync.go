@@ -0,0 +1,138 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+// ErrInvalidCorpus is returned when a corpus is not synced because validation found errors in it
+var ErrInvalidCorpus = errors.New("invalid corpus")
+
+func (i *impl) SyncExcerptsFromFile(ctx context.Context, path string) (db.SyncSummary, error) {
+	sources, err := fileSources(i.cfg, path)
+	if err != nil {
+		return db.SyncSummary{}, err
+	}
+	// the files are read once so validation and import see the same content even if they change in between
+	sources, err = snapshotSources(sources)
+	if err != nil {
+		return db.SyncSummary{}, err
+	}
+	catalogue, err := i.repository.GetCatalogue(ctx)
+	if err != nil {
+		return db.SyncSummary{}, fmt.Errorf("failed to load the catalogue excerpts are resolved against: %w", err)
+	}
+	diagnostics, err := validateSources(ctx, i.cfg, &catalogue, sources)
+	if err != nil {
+		return db.SyncSummary{}, err
+	}
+	if HasErrors(diagnostics) {
+		messages := make([]string, 0, len(diagnostics))
+		for _, d := range diagnostics {
+			messages = append(messages, d.String())
+		}
+		return db.SyncSummary{}, fmt.Errorf("%w:\n%s", ErrInvalidCorpus, strings.Join(messages, "\n"))
+	}
+	excerpts := make([]db.Excerpt, 0)
+	// syncs are counted like imports, their excerpts are all saved or none are
+	var p Progress
+	defer func() {
+		observeImport(p)
+	}()
+	// cancelling stops the decoders when an error ends the sync before every result is consumed
+	parseCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for result := range i.parseSources(parseCtx, sources, i.batchInsertChunkSize) {
+		p.Parsed++
+		err = result.Error
+		if err == nil {
+			err = prepare(catalogue, &result)
+		}
+		if err != nil {
+			return db.SyncSummary{}, err
+		}
+		excerpts = append(excerpts, result.Excerpt)
+	}
+	if ctx.Err() != nil {
+		return db.SyncSummary{}, ctx.Err()
+	}
+	summary, err := i.repository.SyncExcerpts(ctx, excerpts)
+	if err != nil {
+		return db.SyncSummary{}, err
+	}
+	p.Saved, p.Done = len(excerpts), true
+	return summary, nil
+}
+
+func (i *impl) StartWatching(ctx context.Context, path string) {
+	if i.cfg.WatchInterval <= 0 {
+		return
+	}
+	i.watching = make(chan struct{})
+	go func() {
+		defer close(i.watching)
+		t := time.NewTicker(i.cfg.WatchInterval)
+		defer t.Stop()
+		var synced string
+		for {
+			version, err := corpusVersion(path)
+			if err != nil {
+				i.logger.Errorw("failed to check the corpus for changes", "path", path, "error", err)
+			} else if version != synced {
+				summary, err := i.SyncExcerptsFromFile(ctx, path)
+				switch {
+				case err == nil:
+					i.logger.Infow("synced the corpus", "path", path, "summary", summary)
+					synced = version
+				case errors.Is(err, ErrInvalidCorpus):
+					// an invalid version is not retried until it changes again
+					i.logger.Errorw("failed to sync the corpus, keeping the current one", "path", path, "error", err)
+					synced = version
+				default:
+					i.logger.Errorw("failed to sync the corpus, retrying on the next check", "path", path, "error", err)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+		}
+	}()
+}
+
+func (i *impl) StopWatching(ctx context.Context) error {
+	if i.watching == nil {
+		return nil
+	}
+	select {
+	case <-i.watching:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("something wrong happened while waiting for the corpus watcher to stop: %w", ctx.Err())
+	}
+}
+
+// corpusVersion identifies the content of the corpus files at path without reading them, it changes when a file
+// is modified, added or removed
+func corpusVersion(path string) (string, error) {
+	files, err := corpusFiles(path)
+	if err != nil {
+		return "", err
+	}
+	var version strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&version, "%s:%d:%d;", file, info.ModTime().UnixNano(), info.Size())
+	}
+	return version.String(), nil
+}
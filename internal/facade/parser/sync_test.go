@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db/dbtest"
+	"github.com/aaegamysta/listen-2-max-payne/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestSyncExcerptsFromFile(t *testing.T) {
+	repo := &dbtest.Repository{Catalogue: db.Catalogue{
+		Series: []db.CatalogueSeries{{Series: 1, Parts: []db.CataloguePart{
+			{ID: 1, Name: "prologue", Chapters: []db.CatalogueChapter{{ID: 7, Name: "prologue"}}},
+		}}},
+		Characters: []db.CatalogueCharacter{{ID: 3, Name: "Max Payne", Aliases: []string{"Max"}}},
+	}}
+	p := New(context.Background(), Config{}, zap.NewNop().Sugar(), repo)
+	path := filepath.Join(t.TempDir(), "excerpts.json")
+	err := os.WriteFile(path, []byte(`{"excerpts": [
+		{"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "They were all dead.", "speakers": ["max"]}
+	]}`), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write corpus: %v", err)
+	}
+	saved := testutil.ToFloat64(metrics.ImportRecords.WithLabelValues("saved"))
+	summary, err := p.SyncExcerptsFromFile(context.Background(), path)
+	if err != nil || summary.Added != 1 {
+		t.Fatalf("expected one excerpt to be synced, got %v: %v", summary, err)
+	}
+	if counted := testutil.ToFloat64(metrics.ImportRecords.WithLabelValues("saved")) - saved; counted != 1 {
+		t.Fatalf("expected the synced excerpt to be counted as saved, got %v", counted)
+	}
+	e := repo.Synced[0][0]
+	if e.ChapterID != 7 || len(e.SpeakerIDs) != 1 || e.SpeakerIDs[0] != 3 || e.Speakers[0] != "Max Payne" {
+		t.Fatalf("expected the excerpt to be resolved against the catalogue, got %+v", e)
+	}
+	err = os.WriteFile(path, []byte(`{"excerpts": [
+		{"series": 1, "part": "prologue", "chapter": "prolog", "excerpt": "They were all dead."}
+	]}`), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write corpus: %v", err)
+	}
+	_, err = p.SyncExcerptsFromFile(context.Background(), path)
+	if !errors.Is(err, ErrInvalidCorpus) || len(repo.Synced) != 1 {
+		t.Fatalf("expected an invalid corpus not to be synced, got %v", err)
+	}
+}
+
+func TestSyncExcerptsFromFileEditedText(t *testing.T) {
+	repo := &dbtest.Repository{Catalogue: db.Catalogue{
+		Series: []db.CatalogueSeries{{Series: 1, Parts: []db.CataloguePart{
+			{ID: 1, Name: "prologue", Chapters: []db.CatalogueChapter{{ID: 7, Name: "prologue"}}},
+		}}},
+	}}
+	p := New(context.Background(), Config{}, zap.NewNop().Sugar(), repo)
+	path := filepath.Join(t.TempDir(), "excerpts.json")
+	var summary db.SyncSummary
+	for _, text := range []string{"They were all dead.", "They were all dead, the final gunshot an exclamation mark."} {
+		err := os.WriteFile(path, []byte(`{"excerpts": [
+			{"id": "mp1-prologue-1", "series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "`+text+`"}
+		]}`), 0o644)
+		if err != nil {
+			t.Fatalf("failed to write corpus: %v", err)
+		}
+		summary, err = p.SyncExcerptsFromFile(context.Background(), path)
+		if err != nil {
+			t.Fatalf("failed to sync: %v", err)
+		}
+	}
+	if summary != (db.SyncSummary{Changed: 1}) {
+		t.Fatalf("expected the edited excerpt to be changed, got %v", summary)
+	}
+	if len(repo.Excerpts) != 1 || repo.Excerpts[0].ID != "mp1-prologue-1" {
+		t.Fatalf("expected the excerpt to keep its id, got %+v", repo.Excerpts)
+	}
+}
+
+// flakyRepository fails to sync excerpts as many times as failures before syncing them
+type flakyRepository struct {
+	*dbtest.Repository
+	mu       sync.Mutex
+	failures int
+	syncs    int
+}
+
+func (f *flakyRepository) SyncExcerpts(ctx context.Context, excerpts []db.Excerpt) (db.SyncSummary, error) {
+	f.mu.Lock()
+	failing := f.failures > 0
+	f.failures--
+	f.mu.Unlock()
+	if failing {
+		return db.SyncSummary{}, fmt.Errorf("%w: connection refused", db.ErrDatabaseUnavailable)
+	}
+	summary, err := f.Repository.SyncExcerpts(ctx, excerpts)
+	f.mu.Lock()
+	f.syncs++
+	f.mu.Unlock()
+	return summary, err
+}
+
+func (f *flakyRepository) synced() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.syncs
+}
+
+func TestStartWatchingRetriesFailedSyncs(t *testing.T) {
+	repo := &flakyRepository{failures: 2, Repository: &dbtest.Repository{Catalogue: db.Catalogue{
+		Series: []db.CatalogueSeries{{Series: 1, Parts: []db.CataloguePart{
+			{ID: 1, Name: "prologue", Chapters: []db.CatalogueChapter{{ID: 7, Name: "prologue"}}},
+		}}},
+	}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx, Config{WatchInterval: 10 * time.Millisecond}, zap.NewNop().Sugar(), repo)
+	path := filepath.Join(t.TempDir(), "excerpts.json")
+	err := os.WriteFile(path, []byte(`{"excerpts": [
+		{"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "They were all dead."}
+	]}`), 0o644)
+	if err != nil {
+		t.Fatalf("failed to write corpus: %v", err)
+	}
+	p.StartWatching(ctx, path)
+	deadline := time.Now().Add(5 * time.Second)
+	for repo.synced() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	err = p.StopWatching(context.Background())
+	if err != nil {
+		t.Fatalf("failed to stop watching: %v", err)
+	}
+	if repo.synced() != 1 {
+		t.Fatalf("expected the unchanged corpus to be synced once the database is back, got %d syncs", repo.synced())
+	}
+}
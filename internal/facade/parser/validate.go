@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+	"gopkg.in/yaml.v3"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type Diagnostic struct {
+	File     string
+	Position Position
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%s: %s: %s", d.File, d.Position, d.Severity, d.Message)
+}
+
+// HasErrors reports whether any of the diagnostics is an error rather than a warning
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadCatalogue reads the YAML catalogue of parts and chapters excerpts are checked against
+func LoadCatalogue(path string) (db.Catalogue, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return db.Catalogue{}, fmt.Errorf("something wrong happened while reading catalogue file: %w", err)
+	}
+	var catalogue db.Catalogue
+	err = yaml.Unmarshal(b, &catalogue)
+	if err != nil {
+		return db.Catalogue{}, fmt.Errorf("something wrong happened while unmarshalling catalogue file: %w", err)
+	}
+	return catalogue, nil
+}
+
+// Validate checks every excerpt of the file or directory at path without touching the database and reports all
+// the problems found, duplicates are detected across the files of a directory
+func Validate(ctx context.Context, cfg Config, path string) ([]Diagnostic, error) {
+	sources, err := fileSources(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	var catalogue *db.Catalogue
+	if cfg.CataloguePath != "" {
+		c, err := LoadCatalogue(cfg.CataloguePath)
+		if err != nil {
+			return nil, err
+		}
+		catalogue = &c
+	}
+	return validateSources(ctx, cfg, catalogue, sources)
+}
+
+// ValidateReader checks the excerpts decoded from reader, name is used as the file of the diagnostics.
+// Parts, chapters and speakers are only checked when a catalogue is given.
+func ValidateReader(ctx context.Context,
+	cfg Config,
+	catalogue *db.Catalogue,
+	name string,
+	reader io.Reader,
+	format Format,
+) []Diagnostic {
+	// a reader source never fails to open
+	diagnostics, _ := validateSources(ctx, cfg, catalogue, []source{readerSource(name, reader, format)})
+	return diagnostics
+}
+
+func validateSources(ctx context.Context, cfg Config, catalogue *db.Catalogue, sources []source) ([]Diagnostic, error) {
+	v := validator{
+		cfg:       cfg,
+		catalogue: catalogue,
+		seen:      make(map[string]location),
+	}
+	for _, s := range sources {
+		reader, err := s.open()
+		if err != nil {
+			return nil, err
+		}
+		v.name = s.name
+		results := make(chan Result)
+		decodeErrors := make(chan error, 1)
+		go func() {
+			defer close(results)
+			decodeErrors <- s.format.Decode(ctx, cfg, reader, results)
+		}()
+		for result := range results {
+			v.check(result)
+		}
+		_ = reader.Close()
+		err = <-decodeErrors
+		if err != nil {
+			v.report(SeverityError, Position{}, err)
+		}
+	}
+	return v.diagnostics, nil
+}
+
+// location is where an excerpt was first seen, to report duplicates across files
+type location struct {
+	file     string
+	position Position
+}
+
+type validator struct {
+	cfg         Config
+	catalogue   *db.Catalogue
+	name        string
+	seen        map[string]location
+	diagnostics []Diagnostic
+}
+
+func (v *validator) check(result Result) {
+	position := result.Position
+	if result.Error != nil {
+		v.report(SeverityError, position, result.Error)
+		return
+	}
+	e := result.Excerpt
+	if !e.Series.Valid() {
+		v.reportf(SeverityError, position, "unknown series %d", int(e.Series))
+	}
+	if strings.TrimSpace(e.Excerpt) == "" {
+		v.reportf(SeverityError, position, "empty excerpt text")
+	}
+	length := utf8.RuneCountInString(e.Excerpt)
+	switch {
+	case v.cfg.MaxExcerptLength > 0 && length > v.cfg.MaxExcerptLength:
+		v.reportf(SeverityError, position, "excerpt is %d characters long, more than the maximum of %d",
+			length, v.cfg.MaxExcerptLength)
+	case length > twitter.MaxTweetLength:
+		v.reportf(SeverityWarning, position, "excerpt is %d characters long and can only be posted as a thread of %d tweets",
+			length, len(twitter.SplitThread(e.Excerpt)))
+	}
+	if first, ok := v.seen[e.Excerpt]; ok && e.Excerpt != "" {
+		if first.file == v.name {
+			v.reportf(SeverityError, position, "duplicate excerpt, first seen at %s", first.position)
+		} else {
+			v.reportf(SeverityError, position, "duplicate excerpt, first seen at %s:%s", first.file, first.position)
+		}
+	} else {
+		v.seen[e.Excerpt] = location{file: v.name, position: position}
+	}
+	if v.catalogue != nil && e.Series.Valid() {
+		_, err := v.catalogue.Resolve(e.Series, e.Part, e.Chapter)
+		if err != nil {
+			v.report(SeverityError, position, err)
+		}
+	}
+	if v.catalogue != nil {
+		for _, speaker := range e.Speakers {
+			_, err := v.catalogue.ResolveCharacter(speaker)
+			if err != nil {
+				v.report(SeverityError, position, err)
+			}
+		}
+	}
+	for _, field := range []struct{ name, value string }{
+		{name: "excerpt", value: e.Excerpt},
+		{name: "part", value: e.Part},
+		{name: "chapter", value: e.Chapter},
+	} {
+		if field.value != strings.TrimRight(field.value, " \t\r\n") {
+			v.reportf(SeverityWarning, position, "trailing whitespace in %s", field.name)
+		}
+	}
+}
+
+// report uses the position of err when it carries one
+func (v *validator) report(severity Severity, position Position, err error) {
+	var positionError *PositionError
+	if errors.As(err, &positionError) {
+		position = positionError.Position
+		err = positionError.Err
+	}
+	v.reportf(severity, position, "%v", err)
+}
+
+func (v *validator) reportf(severity Severity, position Position, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		File:     v.name,
+		Position: position,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+func TestValidateReader(t *testing.T) {
+	corpus := `{
+  "excerpts": [
+    {"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "They were all dead."},
+    {"series": 7, "part": "prologue", "chapter": "prologue", "excerpt": "Back to the night the pain started."},
+    {"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": ""},
+    {"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "They were all dead."},
+    {"series": 1, "part": "prologue", "chapter": "Prolog", "excerpt": "Hell's Kitchen. "},
+    {"series": 1, "part": "prologue", "chapter": "prologue", "excerpt": "-Had enough? =Unfair!!", "speakers": ["Mona Sux", "Max"]},
+    {"series": "one", "part": "prologue", "chapter": "prologue", "excerpt": "NYPD."}
+  ]
+}`
+	catalogue := &db.Catalogue{Series: []db.CatalogueSeries{
+		{Series: 1, Parts: []db.CataloguePart{{Name: "prologue", Chapters: []db.CatalogueChapter{{Name: "prologue"}}}}},
+	}, Characters: []db.CatalogueCharacter{{Name: "Max Payne", Aliases: []string{"Max"}}, {Name: "Mona Sax"}}}
+	format, err := FormatByName("json")
+	if err != nil {
+		t.Fatalf("failed to find json format: %v", err)
+	}
+	diagnostics := ValidateReader(context.Background(), Config{}, catalogue, "corpus.json", strings.NewReader(corpus), format)
+	expected := []string{
+		"corpus.json:4:5: error: unknown series 7",
+		"corpus.json:5:5: error: empty excerpt text",
+		"corpus.json:6:5: error: duplicate excerpt, first seen at 3:5",
+		`corpus.json:7:5: error: unknown chapter "Prolog" of part "prologue", did you mean "prologue"?`,
+		"corpus.json:7:5: warning: trailing whitespace in excerpt",
+		`corpus.json:8:5: error: unknown character "Mona Sux" of the catalogue, did you mean "Mona Sax"?`,
+	}
+	if len(diagnostics) != len(expected)+1 {
+		t.Fatalf("expected %d diagnostics, got %d: %v", len(expected)+1, len(diagnostics), diagnostics)
+	}
+	for i, e := range expected {
+		if diagnostics[i].String() != e {
+			t.Fatalf("expected diagnostic %q, got %q", e, diagnostics[i])
+		}
+	}
+	if last := diagnostics[len(diagnostics)-1]; last.Position.Line != 9 || last.Position.Column <= 5 {
+		t.Fatalf("expected a type error on line 9, got %q", last)
+	}
+}
+
+func TestValidateMalformed(t *testing.T) {
+	format, err := FormatByName("json")
+	if err != nil {
+		t.Fatalf("failed to find json format: %v", err)
+	}
+	corpus := "{\n  \"excerpts\": [\n    {\"series\": 1,, \"excerpt\": \"x\"}\n  ]\n}"
+	diagnostics := ValidateReader(context.Background(), Config{}, nil, "corpus.json", strings.NewReader(corpus), format)
+	if len(diagnostics) == 0 || diagnostics[0].Position != (Position{Line: 3, Column: 18}) {
+		t.Fatalf("expected a syntax error at 3:18, got %v", diagnostics)
+	}
+}
+
+func TestValidateTestdata(t *testing.T) {
+	if _, err := os.Stat("./testdata/excerpts.json"); err != nil {
+		t.Skip("no test corpus")
+	}
+	diagnostics, err := Validate(context.Background(), Config{}, "./testdata/excerpts.json")
+	if err != nil {
+		t.Fatalf("failed to validate: %v", err)
+	}
+	if HasErrors(diagnostics) {
+		t.Fatalf("expected the test corpus to be valid, got %v", diagnostics)
+	}
+}
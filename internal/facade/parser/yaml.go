@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"gopkg.in/yaml.v3"
+)
+
+// decodeYAML reads one or more documents shaped like the JSON format, each with an excerpts sequence and
+// optional defaults
+func decodeYAML(ctx context.Context, _ Config, reader io.Reader, results chan<- Result) error {
+	decoder := yaml.NewDecoder(reader)
+	for {
+		var document struct {
+			Defaults Defaults    `yaml:"defaults"`
+			Excerpts []yaml.Node `yaml:"excerpts"`
+		}
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("something wrong happened while decoding yaml document: %w", err)
+		}
+		for _, node := range document.Excerpts {
+			var e db.Excerpt
+			position := Position{Line: node.Line, Column: node.Column}
+			err := node.Decode(&e)
+			if err != nil {
+				err = &PositionError{Position: position, Err: err}
+			}
+			document.Defaults.apply(&e)
+			err = send(ctx, results, Result{
+				Excerpt:  e,
+				Position: position,
+				Error:    err,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -1,5 +1,52 @@
 package publisher
 
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
 type Config struct {
 	TweetPeriodPerDay int `yaml:"tweetPeriodPerDay"`
+	// DryRun replaces the Twitter client with a destination that only renders what would be posted
+	DryRun bool `yaml:"dryRun"`
+	// DryRunOutput is the file dry run tweets are appended to, they are logged when it is empty
+	DryRunOutput string `yaml:"dryRunOutput"`
+	// DialogueStyle is how dialogue is written out in tweets, the corpus markers are kept when it is empty
+	DialogueStyle db.DialogueStyle `yaml:"dialogueStyle"`
+	// Filter narrows down the excerpts that are posted, like only the ones of a character for a themed week
+	Filter db.ExcerptFilter `yaml:"filter"`
+	// Schedules replace Filter on the days they are active, the first active one wins
+	Schedules []Schedule `yaml:"schedules"`
+	// Threads posts the excerpts longer than a tweet as a thread of replies, they are posted as a single tweet
+	// otherwise and Twitter refuses them
+	Threads bool `yaml:"threads"`
+}
+
+// Validate reports every missing or invalid field at once
+func (c Config) Validate() error {
+	var errs []error
+	if c.TweetPeriodPerDay < 0 {
+		errs = append(errs, fmt.Errorf("tweetPeriodPerDay %d must not be negative", c.TweetPeriodPerDay))
+	}
+	if !c.DialogueStyle.Valid() {
+		errs = append(errs, fmt.Errorf("dialogueStyle %q is not a known style", c.DialogueStyle))
+	}
+	if c.Filter.Series != db.Unspecified && !c.Filter.Series.Valid() {
+		errs = append(errs, fmt.Errorf("filter.series %d is not a known series", int(c.Filter.Series)))
+	}
+	for index, schedule := range c.Schedules {
+		for _, bound := range []struct{ name, value string }{{"from", schedule.From}, {"to", schedule.To}} {
+			_, err := time.Parse("01-02", bound.value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("schedules[%d].%s %q is not a MM-DD day", index, bound.name, bound.value))
+			}
+		}
+		if schedule.Filter.Series != db.Unspecified && !schedule.Filter.Series.Valid() {
+			errs = append(errs, fmt.Errorf("schedules[%d].filter.series %d is not a known series", index, int(schedule.Filter.Series)))
+		}
+	}
+	return errors.Join(errs...)
 }
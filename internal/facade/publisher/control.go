@@ -0,0 +1,77 @@
+package publisher
+
+import "time"
+
+// Status is the state of the publishing schedule
+type Status struct {
+	Paused bool
+	// Skipping is how many of the next scheduled posts are skipped
+	Skipping int
+	// NextPostAt is when the schedule posts next unless it is paused or skipping, it is zero until publishing starts
+	NextPostAt time.Time
+	// LastTickAt is when the schedule last ticked, paused or not, starting counts as a tick
+	LastTickAt time.Time
+	// TickPeriod is how often the schedule ticks, it is zero until publishing starts
+	TickPeriod time.Duration
+}
+
+func (i *Impl) Pause() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.paused = true
+	i.logger.Info("publishing paused")
+}
+
+func (i *Impl) Resume() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.paused = false
+	i.logger.Info("publishing resumed")
+}
+
+func (i *Impl) Skip() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.skipping++
+	i.logger.Infow("skipping scheduled posts", "skipping", i.skipping)
+}
+
+func (i *Impl) Status() Status {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return Status{
+		Paused:     i.paused,
+		Skipping:   i.skipping,
+		NextPostAt: i.nextPostAt,
+		LastTickAt: i.lastTickAt,
+		TickPeriod: i.tickPeriod,
+	}
+}
+
+// started records that the schedule started ticking every period at now
+func (i *Impl) started(now time.Time, period time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.tickPeriod = period
+	i.lastTickAt = now
+	i.nextPostAt = now.Add(period)
+}
+
+// due records the tick of now and when the schedule posts next and reports whether the scheduled post of now goes
+// ahead, a skipped post is only consumed while the schedule is not paused
+func (i *Impl) due(now time.Time, next time.Time) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.lastTickAt = now
+	i.nextPostAt = next
+	if i.paused {
+		i.logger.Info("publishing is paused, not posting")
+		return false
+	}
+	if i.skipping > 0 {
+		i.skipping--
+		i.logger.Info("skipping this scheduled post")
+		return false
+	}
+	return true
+}
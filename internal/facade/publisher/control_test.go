@@ -0,0 +1,32 @@
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestImpl_Due(t *testing.T) {
+	i := &Impl{logger: zap.NewNop().Sugar()}
+	next := time.Date(2026, time.December, 24, 9, 0, 0, 0, time.UTC)
+	i.Skip()
+	i.Pause()
+	if i.due(next.Add(-time.Minute), next) {
+		t.Fatal("expected no post while paused")
+	}
+	if status := i.Status(); !status.Paused || status.Skipping != 1 || !status.NextPostAt.Equal(next) {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	i.Resume()
+	if i.due(next, next.Add(time.Minute)) {
+		t.Fatal("expected the skipped post not to be posted")
+	}
+	if !i.due(next.Add(time.Minute), next.Add(2*time.Minute)) {
+		t.Fatal("expected the post after the skipped one to be posted")
+	}
+	if status := i.Status(); status.Paused || status.Skipping != 0 || !status.NextPostAt.Equal(next.Add(2*time.Minute)) ||
+		!status.LastTickAt.Equal(next.Add(time.Minute)) {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
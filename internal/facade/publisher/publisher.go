@@ -4,16 +4,41 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/metrics"
 	"github.com/aaegamysta/listen-2-max-payne/internal/queue"
 	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// ErrExcerptInactive is returned when posting an excerpt that is disabled, removed from the corpus or retracted
+var ErrExcerptInactive = errors.New("excerpt is inactive")
+
 type Interface interface {
 	StartPublishingExcerpts(ctx context.Context)
+	// StopPublishingExcerpts waits for the post in flight once the context publishing was started with is done, the
+	// post is aborted when ctx is done first
+	StopPublishingExcerpts(ctx context.Context) error
+	Retract(ctx context.Context, id string, reason string) (int, error)
+	// PostNow posts the excerpt with the given ID right away, or the one the schedule would post next when it is empty.
+	// It returns ErrExcerptInactive for an inactive excerpt unless force is set, and the twitter.TweetError of a post
+	// Twitter refused once the refusal is recorded.
+	PostNow(ctx context.Context, excerptID string, force bool) (db.Excerpt, error)
+	// Preview renders the tweets PostNow would post without posting them
+	Preview(ctx context.Context, excerptID string) (db.Excerpt, []string, error)
+	// Pause stops the schedule from posting until Resume, posting now is still possible
+	Pause()
+	Resume()
+	// Skip skips the next scheduled post, skipping again skips the posts after it too
+	Skip()
+	Status() Status
 }
 
 type Impl struct {
@@ -21,8 +46,27 @@ type Impl struct {
 	tweetPeriodPerDay time.Duration
 	repository        db.Interface
 	twitterClient     twitter.Interface
+	dryRun            bool
+	threads           bool
+	dialogueStyle     db.DialogueStyle
+	filter            db.ExcerptFilter
+	schedules         []Schedule
 	// TODO: Double ended queue to prevent previously tweeted
 	doubleEndedQueue queue.Dequeue
+	// publishing is closed once the publishing loop returns, it is nil when publishing was not started
+	publishing chan struct{}
+	// abortPosts cancels the post in flight, posts are detached from the context publishing was started with so
+	// stopping drains them instead
+	abortPosts context.CancelFunc
+	// posting serializes scheduled and immediate posts so they never pick the same excerpt
+	posting sync.Mutex
+	// mu guards the state of the schedule below
+	mu         sync.Mutex
+	paused     bool
+	skipping   int
+	nextPostAt time.Time
+	lastTickAt time.Time
+	tickPeriod time.Duration
 }
 
 func New(logger *zap.SugaredLogger, cfg Config, repository db.Interface, twitterClient twitter.Interface) Interface {
@@ -31,59 +75,307 @@ func New(logger *zap.SugaredLogger, cfg Config, repository db.Interface, twitter
 		repository:        repository,
 		twitterClient:     twitterClient,
 		tweetPeriodPerDay: time.Duration(cfg.TweetPeriodPerDay),
+		dryRun:            cfg.DryRun,
+		threads:           cfg.Threads,
+		dialogueStyle:     cfg.DialogueStyle,
+		filter:            cfg.Filter,
+		schedules:         cfg.Schedules,
 		doubleEndedQueue:  queue.New(20),
 	}
 }
 
 func (i *Impl) StartPublishingExcerpts(ctx context.Context) {
+	postCtx, abort := context.WithCancel(context.WithoutCancel(ctx))
+	i.abortPosts = abort
+	i.publishing = make(chan struct{})
 	go func() {
+		defer close(i.publishing)
+		defer abort()
 		// for purpose of testing setting it as a minute
-		t := time.NewTicker(1 * time.Minute)
+		period := 1 * time.Minute
+		t := time.NewTicker(period)
 		// t := time.NewTicker(1 * i.tweetPeriodPerDay)
+		defer t.Stop()
+		i.started(time.Now(), period)
+		metrics.ObserveNextPost(func() (time.Time, bool) {
+			status := i.Status()
+			return status.NextPostAt, !status.Paused && !status.NextPostAt.IsZero()
+		})
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case <-t.C:
-				err := i.tweet(ctx)
-				if err != nil {
-					i.logger.Errorf("failed to tweet excerpt: %v, skipping this one", err)
-				}
+			case now := <-t.C:
+				i.tick(postCtx, now, now.Add(period))
 			}
 		}
 	}()
 }
 
+// tick posts the scheduled excerpt of now when it is due, next is when the schedule ticks again
+func (i *Impl) tick(ctx context.Context, now time.Time, next time.Time) {
+	ctx, span := tracer.Start(ctx, "schedule tick")
+	defer span.End()
+	due := i.due(now, next)
+	span.SetAttributes(attribute.Bool("schedule.due", due))
+	if !due {
+		return
+	}
+	err := fail(span, i.tweet(ctx))
+	if err != nil {
+		i.logger.Errorw("failed to post the scheduled excerpt, skipping this one", "error", err)
+	}
+}
+
+func (i *Impl) StopPublishingExcerpts(ctx context.Context) error {
+	if i.publishing == nil {
+		return nil
+	}
+	select {
+	case <-i.publishing:
+		return nil
+	case <-ctx.Done():
+		i.logger.Warn("aborting the post in flight, it did not complete in time")
+		i.abortPosts()
+		<-i.publishing
+		return fmt.Errorf("something wrong happened while draining the post in flight: %w", ctx.Err())
+	}
+}
+
 func (i *Impl) tweet(ctx context.Context) error {
-	excerpt, err := i.repository.GetRandomExcerpt(ctx)
+	i.posting.Lock()
+	defer i.posting.Unlock()
+	excerpt, err := i.nextExcerpt(ctx)
 	if err != nil {
-		i.logger.Errorf("failed to retrieve random excerpt for tweeting: %v", err)
+		return err
+	}
+	err = i.publish(ctx, excerpt)
+	// a refused post is already logged and recorded, the schedule moves on to the next one
+	var refused twitter.TweetError
+	if errors.As(err, &refused) {
+		return nil
+	}
+	return err
+}
+
+func (i *Impl) PostNow(ctx context.Context, excerptID string, force bool) (db.Excerpt, error) {
+	ctx, span := tracer.Start(ctx, "post now")
+	defer span.End()
+	i.posting.Lock()
+	defer i.posting.Unlock()
+	var excerpt db.Excerpt
+	var err error
+	if excerptID != "" {
+		excerpt, err = i.repository.GetExcerpt(ctx, excerptID)
+	} else {
+		excerpt, err = i.nextExcerpt(ctx)
+	}
+	if err != nil {
+		return db.Excerpt{}, fail(span, err)
+	}
+	if excerpt.Inactive() && !force {
+		return db.Excerpt{}, fail(span, inactiveError(excerpt))
 	}
-	for !i.doubleEndedQueue.Empty() && i.doubleEndedQueue.Peek().Excerpt == excerpt.Excerpt {
-		excerpt, err = i.repository.GetRandomExcerpt(ctx)
+	return excerpt, fail(span, i.publish(ctx, excerpt))
+}
+
+// inactiveError wraps ErrExcerptInactive with the reasons the excerpt is inactive
+func inactiveError(excerpt db.Excerpt) error {
+	var reasons []string
+	if excerpt.Disabled {
+		reasons = append(reasons, "disabled")
+	}
+	if excerpt.Removed {
+		reasons = append(reasons, "removed from the corpus")
+	}
+	if excerpt.Retracted {
+		reasons = append(reasons, "retracted")
+	}
+	return fmt.Errorf("%w: %s is %s, force the post to publish it anyway", ErrExcerptInactive, excerpt.ID,
+		strings.Join(reasons, " and "))
+}
+
+func (i *Impl) Preview(ctx context.Context, excerptID string) (db.Excerpt, []string, error) {
+	var excerpt db.Excerpt
+	var err error
+	if excerptID != "" {
+		excerpt, err = i.repository.GetExcerpt(ctx, excerptID)
+	} else {
+		// the recently posted queue is left untouched since nothing is posted
+		excerpt, err = i.randomExcerpt(ctx, time.Now())
+	}
+	if err != nil {
+		return db.Excerpt{}, nil, err
+	}
+	return excerpt, i.thread(excerpt), nil
+}
+
+// maxSelectionAttempts bounds how many excerpts are drawn to avoid posting the one posted last, a filter matching a
+// single excerpt keeps drawing it
+const maxSelectionAttempts = 3
+
+// nextExcerpt picks a random excerpt of the active schedule that is not the one posted last, unless it is the only
+// one drawn in maxSelectionAttempts
+func (i *Impl) nextExcerpt(ctx context.Context) (db.Excerpt, error) {
+	ctx, span := tracer.Start(ctx, "select excerpt")
+	defer span.End()
+	now := time.Now()
+	excerpt, err := i.randomExcerpt(ctx, now)
+	if err != nil {
+		return db.Excerpt{}, fail(span, fmt.Errorf("failed to retrieve random excerpt for tweeting: %w", err))
+	}
+	attempt := 1
+	for i.postedLast(excerpt) && attempt < maxSelectionAttempts {
+		attempt++
+		metrics.SelectionRetries.WithLabelValues("posted_last").Inc()
+		i.logger.Debugw("picked the excerpt posted last, picking again", "excerpt_id", excerpt.ID, "attempt", attempt)
+		excerpt, err = i.randomExcerpt(ctx, now)
 		if err != nil {
-			return fmt.Errorf(`failed to continuously retrieve random excerpt because front element is equal to the fetched excerpt: %w`,
-				err)
+			return db.Excerpt{}, fail(span, fmt.Errorf(`failed to continuously retrieve random excerpt because front element is equal to the fetched excerpt: %w`,
+				err))
 		}
 	}
+	if i.postedLast(excerpt) {
+		i.logger.Infow("kept picking the excerpt posted last, posting it again", "excerpt_id", excerpt.ID, "attempts", attempt)
+	}
+	span.SetAttributes(attribute.String("excerpt.id", excerpt.ID), attribute.Int("selection.attempts", attempt))
 	_, _ = i.doubleEndedQueue.Dequeue()
 	_ = i.doubleEndedQueue.Enqueue(excerpt)
-	successfulTweetRes, err := i.twitterClient.Post(ctx, twitter.Tweet{
-		Text: excerpt.Excerpt,
-	})
+	return excerpt, nil
+}
+
+// postedLast tells whether the excerpt is the one posted last
+func (i *Impl) postedLast(excerpt db.Excerpt) bool {
+	return !i.doubleEndedQueue.Empty() && i.doubleEndedQueue.Peek().Excerpt == excerpt.Excerpt
+}
+
+// publish posts the excerpt and records the outcome in the posting history, a post Twitter refused is returned as
+// its twitter.TweetError once the refusal is recorded
+func (i *Impl) publish(ctx context.Context, excerpt db.Excerpt) error {
+	destination := i.destination()
+	ctx, span := tracer.Start(ctx, "publish excerpt", trace.WithAttributes(
+		attribute.String("excerpt.id", excerpt.ID),
+		attribute.String("publisher.destination", destination),
+	))
+	defer span.End()
+	metrics.PostsAttempted.WithLabelValues(destination).Inc()
+	logger := i.logger.With("excerpt_id", excerpt.ID, "destination", destination)
+	if span.SpanContext().IsSampled() {
+		logger = logger.With("trace_id", span.SpanContext().TraceID().String())
+	}
+	logger.Debugw("posting excerpt", "excerpt", excerpt.Excerpt)
+	thread, postErr := i.postThread(ctx, logger, i.render(ctx, excerpt))
+	span.SetAttributes(attribute.Int("publisher.tweets", len(thread)))
+	if postErr != nil {
+		_ = fail(span, postErr)
+		metrics.PostsFailed.WithLabelValues(destination, errorType(postErr)).Inc()
+		logger.Warnw("failed to post excerpt", "error_type", errorType(postErr), "tweets_posted", len(thread), "error", postErr)
+	} else {
+		metrics.PostsSucceeded.WithLabelValues(destination).Inc()
+	}
+	err := i.record(ctx, excerpt, thread, postErr)
+	if err != nil {
+		if postErr == nil {
+			_ = fail(span, err)
+		}
+		return err
+	}
+	if postErr != nil {
+		return fmt.Errorf("twitter refused to post excerpt %s: %w", excerpt.ID, postErr)
+	}
+	if len(thread) > 0 {
+		logger.Infow("posted excerpt", "tweet_id", thread[0].Data.ID, "tweets", len(thread))
+	}
+	return nil
+}
+
+// record records the outcome of posting the excerpt in the posting history, it returns postErr unless Twitter
+// refused the post and the refusal is recorded
+func (i *Impl) record(ctx context.Context, excerpt db.Excerpt, thread []twitter.SucessfullTweetResponse, postErr error) error {
+	ctx, span := tracer.Start(ctx, "record post")
+	defer span.End()
+	// the dry run destination keeps its own history so nothing is recorded in the posting history
+	if len(thread) > 0 && !i.dryRun {
+		insertErr := i.repository.InsertSuccessfulTweetResponse(ctx, excerpt, thread)
+		if insertErr != nil {
+			return fail(span, fmt.Errorf("failed to insert successful tweet response but it was at least tweeted: %w", insertErr))
+		}
+	}
 
 	var unsuccessfullTweetResponse twitter.TweetError
-	if errors.As(err, &unsuccessfullTweetResponse) {
+	if errors.As(postErr, &unsuccessfullTweetResponse) {
 		// here an error can be generated if the unsuccsesful tweet response is not sent
-		return i.repository.InsertUnsuccessfulTweetResponse(ctx, excerpt, twitter.TweetError{})
-	} else if err != nil {
-		return err
+		return fail(span, i.repository.InsertUnsuccessfulTweetResponse(ctx, excerpt, unsuccessfullTweetResponse))
 	}
+	return postErr
+}
 
-	err = i.repository.InsertSuccessfulTweetResponse(ctx, successfulTweetRes)
-	if err != nil {
-		return fmt.Errorf("failed to insert successful tweet response but it was at least tweeted: %w", err)
+// render renders the excerpt into the tweets it is posted as
+func (i *Impl) render(ctx context.Context, excerpt db.Excerpt) []string {
+	_, span := tracer.Start(ctx, "render excerpt")
+	defer span.End()
+	parts := i.thread(excerpt)
+	span.SetAttributes(attribute.Int("publisher.parts", len(parts)))
+	return parts
+}
+
+// postThread posts the parts as a single tweet or, when there are more than one, as a thread of replies to the
+// first tweet. The tweets posted before a failure are returned alongside the error.
+func (i *Impl) postThread(ctx context.Context,
+	logger *zap.SugaredLogger,
+	parts []string,
+) ([]twitter.SucessfullTweetResponse, error) {
+	thread := make([]twitter.SucessfullTweetResponse, 0, len(parts))
+	for _, part := range parts {
+		tweet := twitter.Tweet{
+			Text: part,
+		}
+		if len(thread) > 0 {
+			tweet.Reply = &twitter.TweetReply{InReplyToTweetID: thread[len(thread)-1].Data.ID}
+		}
+		res, err := i.twitterClient.Post(ctx, tweet)
+		if err != nil {
+			return thread, err
+		}
+		logger.Debugw("posted tweet", "tweet_id", res.Data.ID, "part", len(thread)+1, "parts", len(parts))
+		thread = append(thread, res)
 	}
-	i.logger.Infof("tweeted excerpt: %v on %s", successfulTweetRes.Data.Text, time.Now())
-	return nil
+	return thread, nil
+}
+
+func (i *Impl) destination() string {
+	if i.dryRun {
+		return "dry-run"
+	}
+	return "twitter"
+}
+
+// errorType classifies the errors of posts for the metrics, by the status Twitter responded with when it did
+func errorType(err error) string {
+	var tweetError twitter.TweetError
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "aborted"
+	case !errors.As(err, &tweetError):
+		return "request"
+	case tweetError.Status == http.StatusUnauthorized:
+		return "unauthorized"
+	case tweetError.Status == http.StatusForbidden:
+		return "forbidden"
+	case tweetError.Status == http.StatusTooManyRequests:
+		return "rate_limited"
+	case tweetError.Status >= http.StatusInternalServerError:
+		return "unavailable"
+	default:
+		return "rejected"
+	}
+}
+
+// thread renders the excerpt in the dialogue style and splits it into the tweets it is posted as
+func (i *Impl) thread(excerpt db.Excerpt) []string {
+	text := i.dialogueStyle.Render(excerpt)
+	if !i.threads {
+		return []string{text}
+	}
+	return twitter.SplitThread(text)
 }
@@ -0,0 +1,255 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db/dbtest"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter/twittertest"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+)
+
+func newTestPublisher(t *testing.T, cfg Config, repo db.Interface) (*Impl, *twittertest.Server) {
+	t.Helper()
+	server := twittertest.NewServer()
+	t.Cleanup(server.Close)
+	logger := zap.NewNop().Sugar()
+	client := twitter.New(context.Background(), server.Config(), logger, nil, nil)
+	return New(logger, cfg, repo, client).(*Impl), server
+}
+
+func TestImpl_tweetThread(t *testing.T) {
+	repo := &dbtest.Repository{
+		Excerpts: []db.Excerpt{{Excerpt: strings.TrimSpace(strings.Repeat("Nothing to lose. ", 30))}},
+	}
+	p, server := newTestPublisher(t, Config{Threads: true}, repo)
+	err := p.tweet(context.Background())
+	if err != nil {
+		t.Fatalf("failed to tweet: %v", err)
+	}
+	posted := server.Tweets()
+	if len(posted) < 2 {
+		t.Fatalf("expected a thread, got %d tweets", len(posted))
+	}
+	for i := 1; i < len(posted); i++ {
+		if posted[i].Tweet.Reply == nil || posted[i].Tweet.Reply.InReplyToTweetID != posted[i-1].ID {
+			t.Fatalf("tweet %d is not a reply to the previous one: %+v", i, posted[i])
+		}
+	}
+	if len(repo.Posts) != 1 || len(repo.Posts[0].ThreadTweetIDs) != len(posted)-1 {
+		t.Fatalf("expected the whole thread to be recorded once, got %+v", repo.Posts)
+	}
+}
+
+func TestImpl_tweetWithoutThreads(t *testing.T) {
+	text := strings.TrimSpace(strings.Repeat("Nothing to lose. ", 30))
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{{Excerpt: text}}}
+	p, server := newTestPublisher(t, Config{}, repo)
+	err := p.tweet(context.Background())
+	if err != nil {
+		t.Fatalf("failed to tweet: %v", err)
+	}
+	posted := server.Tweets()
+	if len(posted) != 1 || posted[0].Tweet.Text != text || posted[0].Tweet.Reply != nil {
+		t.Fatalf("expected the excerpt to be posted as a single tweet, got %+v", posted)
+	}
+	if len(repo.Posts) != 1 || len(repo.Posts[0].ThreadTweetIDs) != 0 {
+		t.Fatalf("expected a single tweet to be recorded, got %+v", repo.Posts)
+	}
+}
+
+func TestImpl_tweetFailure(t *testing.T) {
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{{Excerpt: "Pain and suffering."}}}
+	p, server := newTestPublisher(t, Config{}, repo)
+	server.FailNext(twittertest.ErrDuplicate)
+	err := p.tweet(context.Background())
+	if err != nil {
+		t.Fatalf("expected the failure to be recorded instead of returned: %v", err)
+	}
+	if len(repo.Failed) != 1 || repo.Failed[0].Status != twittertest.ErrDuplicate.Status {
+		t.Fatalf("expected the duplicate error to be recorded, got %+v", repo.Failed)
+	}
+	if len(repo.Posts) != 0 {
+		t.Fatalf("expected nothing to be recorded as successful")
+	}
+}
+
+func TestImpl_PostNowRefused(t *testing.T) {
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{{ID: "4f1c", Excerpt: "Pain and suffering."}}}
+	p, server := newTestPublisher(t, Config{}, repo)
+	server.FailNext(twittertest.ErrDuplicate)
+	_, err := p.PostNow(context.Background(), "4f1c", false)
+	var refused twitter.TweetError
+	if !errors.As(err, &refused) || refused.Status != twittertest.ErrDuplicate.Status {
+		t.Fatalf("expected the refusal to be returned, got %v", err)
+	}
+	if len(repo.Failed) != 1 {
+		t.Fatalf("expected the refusal to be recorded, got %+v", repo.Failed)
+	}
+}
+
+func TestImpl_Retract(t *testing.T) {
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{
+		{ID: "6d2f1c0a9b8e7d6c", Excerpt: strings.TrimSpace(strings.Repeat("In the land of the blind. ", 20))},
+	}}
+	p, server := newTestPublisher(t, Config{Threads: true}, repo)
+	err := p.tweet(context.Background())
+	if err != nil {
+		t.Fatalf("failed to tweet: %v", err)
+	}
+	posted := server.Tweets()
+	retracted, err := p.Retract(context.Background(), repo.Excerpts[0].ID, "typo")
+	if err != nil || retracted != 1 {
+		t.Fatalf("expected one post to be retracted, got %d: %v", retracted, err)
+	}
+	if len(server.Tweets()) != 0 {
+		t.Fatalf("expected every tweet of the thread to be deleted, %d are left", len(server.Tweets()))
+	}
+	deleted := server.Deleted()
+	if deleted[len(deleted)-1] != posted[0].ID {
+		t.Fatalf("expected the root tweet to be deleted last, got %v", deleted)
+	}
+	if len(repo.Retracted) != 1 || repo.Retracted[0] != posted[0].ID {
+		t.Fatalf("expected the post to be marked as retracted, got %v", repo.Retracted)
+	}
+}
+
+func TestImpl_RetractByReply(t *testing.T) {
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{
+		{ID: "6d2f1c0a9b8e7d6c", Excerpt: strings.TrimSpace(strings.Repeat("In the land of the blind. ", 20))},
+	}}
+	p, server := newTestPublisher(t, Config{Threads: true}, repo)
+	err := p.tweet(context.Background())
+	if err != nil {
+		t.Fatalf("failed to tweet: %v", err)
+	}
+	posted := server.Tweets()
+	retracted, err := p.Retract(context.Background(), posted[len(posted)-1].ID, "typo")
+	if err != nil || retracted != 1 {
+		t.Fatalf("expected the post the reply belongs to to be retracted, got %d: %v", retracted, err)
+	}
+	if len(server.Tweets()) != 0 || len(repo.Retracted) != 1 || repo.Retracted[0] != posted[0].ID {
+		t.Fatalf("expected the whole thread to be deleted and retracted, %d tweets are left and %v retracted",
+			len(server.Tweets()), repo.Retracted)
+	}
+}
+
+// failingRepository fails to mark posts retracted once it marked as many as marked
+type failingRepository struct {
+	*dbtest.Repository
+	marked int
+}
+
+func (f *failingRepository) MarkPostRetracted(ctx context.Context, tweetID string, reason string) error {
+	if len(f.Retracted) >= f.marked {
+		return errors.New("connection reset by peer")
+	}
+	return f.Repository.MarkPostRetracted(ctx, tweetID, reason)
+}
+
+func TestImpl_RetractPartially(t *testing.T) {
+	excerpt := db.Excerpt{ID: "6d2f1c0a9b8e7d6c", Excerpt: "In the land of the blind."}
+	repo := &failingRepository{marked: 1, Repository: &dbtest.Repository{Posts: []db.PostedExcerpt{
+		{Excerpt: excerpt, TweetID: "1001"},
+		{Excerpt: excerpt, TweetID: "1002"},
+	}}}
+	p, _ := newTestPublisher(t, Config{}, repo)
+	retracted, err := p.Retract(context.Background(), excerpt.ID, "typo")
+	if err == nil || retracted != 1 {
+		t.Fatalf("expected the first post to be retracted before failing, got %d: %v", retracted, err)
+	}
+}
+
+func TestImpl_nextExcerptSingleMatch(t *testing.T) {
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{
+		{ID: "6d2f1c0a9b8e7d6c", Excerpt: "In the land of the blind.", Speakers: []string{"Max Payne"}},
+		{ID: "0b1e2c3d4f5a6b7c", Excerpt: "They were all dead.", Speakers: []string{"Mona Sax"}},
+	}}
+	p, _ := newTestPublisher(t, Config{Filter: db.ExcerptFilter{Character: "Mona Sax"}}, repo)
+	for range 2 {
+		excerpt, err := p.nextExcerpt(context.Background())
+		if err != nil || excerpt.ID != "0b1e2c3d4f5a6b7c" {
+			t.Fatalf("expected the only excerpt matching the filter to be picked, got %+v: %v", excerpt, err)
+		}
+	}
+	if len(repo.Filters) != 1+maxSelectionAttempts {
+		t.Fatalf("expected the repeat to be accepted after %d attempts, got %d selections", maxSelectionAttempts,
+			len(repo.Filters)-1)
+	}
+}
+
+func TestImpl_PostNowInactive(t *testing.T) {
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{
+		{ID: "6d2f1c0a9b8e7d6c", Excerpt: "In the land of the blind."},
+		{ID: "0b1e2c3d4f5a6b7c", Excerpt: "They were all dead.", Disabled: true},
+		{ID: "9f8e7d6c5b4a3f2e", Excerpt: "Hell's Kitchen.", Removed: true},
+	}}
+	p, server := newTestPublisher(t, Config{}, repo)
+	ctx := context.Background()
+	_, err := p.PostNow(ctx, repo.Excerpts[0].ID, false)
+	if err != nil {
+		t.Fatalf("failed to post now: %v", err)
+	}
+	_, err = p.Retract(ctx, repo.Excerpts[0].ID, "typo")
+	if err != nil {
+		t.Fatalf("failed to retract: %v", err)
+	}
+	for _, e := range repo.Excerpts {
+		_, err = p.PostNow(ctx, e.ID, false)
+		if !errors.Is(err, ErrExcerptInactive) {
+			t.Fatalf("expected posting %s to be refused, got %v", e.ID, err)
+		}
+	}
+	if len(server.Tweets()) != 0 {
+		t.Fatalf("expected nothing to be posted, got %d tweets", len(server.Tweets()))
+	}
+	for _, e := range repo.Excerpts {
+		_, err = p.PostNow(ctx, e.ID, true)
+		if err != nil {
+			t.Fatalf("failed to force posting %s: %v", e.ID, err)
+		}
+	}
+	if len(server.Tweets()) != len(repo.Excerpts) {
+		t.Fatalf("expected every excerpt to be posted when forced, got %d tweets", len(server.Tweets()))
+	}
+}
+
+func TestImpl_tickTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		_ = provider.Shutdown(context.Background())
+	})
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{{ID: "mp1-p1-c1-1", Excerpt: "They were all dead."}}}
+	p, _ := newTestPublisher(t, Config{}, repo)
+
+	now := time.Now()
+	p.tick(context.Background(), now, now.Add(time.Minute))
+	spans := exporter.GetSpans()
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	// spans are exported as they end, children before their parent
+	expected := "select excerpt,render excerpt,twitter POST /2/tweets,record post,publish excerpt,schedule tick"
+	if strings.Join(names, ",") != expected {
+		t.Fatalf("expected the spans %s, got %s", expected, strings.Join(names, ","))
+	}
+	root := spans[len(spans)-1].SpanContext.TraceID()
+	for _, span := range spans {
+		if span.SpanContext.TraceID() != root {
+			t.Fatalf("expected every span to belong to the trace of the tick, %s does not", span.Name)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+)
+
+// Retract deletes every tweet, thread replies included, of the posts matching id which is either an excerpt ID
+// or a tweet ID, then marks the posts as retracted. It returns the number of retracted posts, the ones retracted before a
+// failure included.
+func (i *Impl) Retract(ctx context.Context, id string, reason string) (int, error) {
+	posts, err := i.repository.FindPostsToRetract(ctx, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find posts to retract: %w", err)
+	}
+	if len(posts) == 0 {
+		return 0, fmt.Errorf("there is no post left to retract for %s", id)
+	}
+	retracted := 0
+	for _, post := range posts {
+		// replies are deleted before the tweet they reply to so a failure never leaves orphaned replies behind
+		tweetIDs := append([]string{post.TweetID}, post.ThreadTweetIDs...)
+		for j := len(tweetIDs) - 1; j >= 0; j-- {
+			err = i.twitterClient.Delete(ctx, tweetIDs[j])
+			var tweetError twitter.TweetError
+			if errors.As(err, &tweetError) && tweetError.Status == http.StatusNotFound {
+				i.logger.Warnw("tweet was already deleted", "excerpt_id", post.Excerpt.ID, "tweet_id", tweetIDs[j])
+			} else if err != nil {
+				return retracted, fmt.Errorf("failed to delete tweet %s of excerpt %s: %w", tweetIDs[j], post.Excerpt.ID, err)
+			}
+		}
+		err = i.repository.MarkPostRetracted(ctx, post.TweetID, reason)
+		if err != nil {
+			return retracted, fmt.Errorf("deleted the tweets of excerpt %s but failed to mark them as retracted: %w", post.Excerpt.ID, err)
+		}
+		retracted++
+		i.logger.Infow("retracted excerpt", "excerpt_id", post.Excerpt.ID, "tweet_id", post.TweetID, "posted_on", post.PostedOn)
+	}
+	return retracted, nil
+}
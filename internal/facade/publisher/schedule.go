@@ -0,0 +1,54 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+)
+
+// Schedule posts only the excerpts matching its filter on the days it is active, like winter tagged excerpts in December
+type Schedule struct {
+	Name string `yaml:"name"`
+	// From and To are the inclusive MM-DD bounds of the days the schedule is active on, a range wrapping around
+	// the new year like 12-20 to 01-06 is allowed
+	From   string           `yaml:"from"`
+	To     string           `yaml:"to"`
+	Filter db.ExcerptFilter `yaml:"filter"`
+}
+
+func (s Schedule) active(now time.Time) bool {
+	day := now.Format("01-02")
+	if s.From <= s.To {
+		return s.From <= day && day <= s.To
+	}
+	return day >= s.From || day <= s.To
+}
+
+// activeSchedule returns the first schedule active on now, the default filter applies when there is none
+func (i *Impl) activeSchedule(now time.Time) (Schedule, bool) {
+	for _, schedule := range i.schedules {
+		if schedule.active(now) {
+			return schedule, true
+		}
+	}
+	return Schedule{}, false
+}
+
+// randomExcerpt picks a random excerpt of the schedule active on now, falling back to the default filter when the
+// schedule matches no excerpt so a schedule with a typo in its tags does not stop the bot from posting
+func (i *Impl) randomExcerpt(ctx context.Context, now time.Time) (db.Excerpt, error) {
+	schedule, ok := i.activeSchedule(now)
+	if !ok {
+		return i.repository.GetRandomExcerpt(ctx, i.filter)
+	}
+	i.logger.Debugw("picking an excerpt from a schedule", "schedule", schedule.Name)
+	excerpt, err := i.repository.GetRandomExcerpt(ctx, schedule.Filter)
+	if errors.Is(err, db.ErrExcerptNotFound) {
+		i.logger.Warnw("the active schedule matches no excerpt, falling back to the default filter",
+			"schedule", schedule.Name, "error", err)
+		return i.repository.GetRandomExcerpt(ctx, i.filter)
+	}
+	return excerpt, err
+}
@@ -0,0 +1,89 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/db"
+	"github.com/aaegamysta/listen-2-max-payne/internal/db/dbtest"
+	"go.uber.org/zap"
+)
+
+func TestImpl_activeSchedule(t *testing.T) {
+	winter := db.ExcerptFilter{Tags: []string{"winter"}}
+	holidays := db.ExcerptFilter{Tags: []string{"new york winter"}}
+	i := &Impl{
+		logger: zap.NewNop().Sugar(),
+		filter: db.ExcerptFilter{Character: "Max Payne"},
+		schedules: []Schedule{
+			{Name: "holidays", From: "12-20", To: "01-06", Filter: holidays},
+			{Name: "winter", From: "12-01", To: "12-31", Filter: winter},
+		},
+	}
+	tests := []struct {
+		day      time.Time
+		expected db.ExcerptFilter
+	}{
+		{day: time.Date(2026, time.December, 1, 9, 0, 0, 0, time.UTC), expected: winter},
+		{day: time.Date(2026, time.December, 24, 9, 0, 0, 0, time.UTC), expected: holidays},
+		{day: time.Date(2027, time.January, 6, 23, 0, 0, 0, time.UTC), expected: holidays},
+		{day: time.Date(2027, time.January, 7, 0, 0, 0, 0, time.UTC), expected: i.filter},
+	}
+	for _, tt := range tests {
+		filter := i.filter
+		if schedule, ok := i.activeSchedule(tt.day); ok {
+			filter = schedule.Filter
+		}
+		if !reflect.DeepEqual(filter, tt.expected) {
+			t.Fatalf("expected filter %+v on %s, got %+v", tt.expected, tt.day, filter)
+		}
+	}
+}
+
+func TestImpl_randomExcerptEmptySchedule(t *testing.T) {
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{
+		{ID: "4f1c", Excerpt: "They were all dead.", Tags: []string{"noir"}},
+	}}
+	i := &Impl{
+		logger:     zap.NewNop().Sugar(),
+		repository: repo,
+		schedules: []Schedule{
+			{Name: "winter", From: "12-01", To: "12-31", Filter: db.ExcerptFilter{Tags: []string{"wintre"}}},
+		},
+	}
+	excerpt, err := i.randomExcerpt(context.Background(), time.Date(2026, time.December, 24, 9, 0, 0, 0, time.UTC))
+	if err != nil || excerpt.ID != "4f1c" {
+		t.Fatalf("expected to fall back to the default filter, got %+v: %v", excerpt, err)
+	}
+	if len(repo.Filters) != 2 || !reflect.DeepEqual(repo.Filters[1], db.ExcerptFilter{}) {
+		t.Fatalf("expected the schedule then the default filter to be tried, got %+v", repo.Filters)
+	}
+	repo.Excerpts = nil
+	_, err = i.randomExcerpt(context.Background(), time.Date(2026, time.December, 24, 9, 0, 0, 0, time.UTC))
+	if !errors.Is(err, db.ErrExcerptNotFound) {
+		t.Fatalf("expected no excerpt to be found in an empty corpus, got %v", err)
+	}
+}
+
+func TestImpl_nextExcerptSingleMatchSchedule(t *testing.T) {
+	repo := &dbtest.Repository{Excerpts: []db.Excerpt{
+		{ID: "4f1c", Excerpt: "They were all dead.", Tags: []string{"noir"}},
+		{ID: "9a2b", Excerpt: "The snow kept falling.", Tags: []string{"winter"}},
+	}}
+	p, _ := newTestPublisher(t, Config{Schedules: []Schedule{
+		{Name: "all year", From: "01-01", To: "12-31", Filter: db.ExcerptFilter{Tags: []string{"winter"}}},
+	}}, repo)
+	for range 2 {
+		excerpt, err := p.nextExcerpt(context.Background())
+		if err != nil || excerpt.ID != "9a2b" {
+			t.Fatalf("expected the only excerpt of the schedule to be picked, got %+v: %v", excerpt, err)
+		}
+	}
+	if len(repo.Filters) != 1+maxSelectionAttempts {
+		t.Fatalf("expected the repeat to be accepted after %d attempts, got %d selections", maxSelectionAttempts,
+			len(repo.Filters)-1)
+	}
+}
@@ -0,0 +1,18 @@
+package publisher
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/aaegamysta/listen-2-max-payne/internal/facade/publisher")
+
+// fail records err on span, when there is one, and returns it
+func fail(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	EncodingConsole = "console"
+	EncodingJSON    = "json"
+)
+
+type Config struct {
+	// Level is the minimum level logged, one of debug, info, warn and error, info when empty
+	Level string `yaml:"level"`
+	// Encoding is console, the default, for people or json for log collectors
+	Encoding string         `yaml:"encoding"`
+	Sampling SamplingConfig `yaml:"sampling"`
+	// Output is stderr, the default, stdout or the path of a file to append to
+	Output string `yaml:"output"`
+}
+
+// SamplingConfig caps the entries logged per second with the same level and message, sampling is disabled when
+// Initial is zero
+type SamplingConfig struct {
+	// Initial entries are logged every second before sampling starts
+	Initial int `yaml:"initial"`
+	// Thereafter only every Thereafter-th entry is logged for the rest of the second, none are when it is zero
+	Thereafter int `yaml:"thereafter"`
+}
+
+// Validate reports every invalid field at once, every field is optional
+func (c Config) Validate() error {
+	var errs []error
+	if c.Level != "" {
+		_, err := zapcore.ParseLevel(c.Level)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("level %q is not one of debug, info, warn and error", c.Level))
+		}
+	}
+	switch c.Encoding {
+	case "", EncodingConsole, EncodingJSON:
+	default:
+		errs = append(errs, fmt.Errorf("encoding %q is neither %s nor %s", c.Encoding, EncodingConsole, EncodingJSON))
+	}
+	if c.Sampling.Initial < 0 {
+		errs = append(errs, fmt.Errorf("sampling.initial %d must not be negative", c.Sampling.Initial))
+	}
+	if c.Sampling.Thereafter < 0 {
+		errs = append(errs, fmt.Errorf("sampling.thereafter %d must not be negative", c.Sampling.Thereafter))
+	}
+	return errors.Join(errs...)
+}
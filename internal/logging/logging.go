@@ -0,0 +1,48 @@
+// Package logging builds the logger of the bot from its configuration, every entry goes through a Redactor so
+// secrets never reach the logs
+package logging
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New builds the logger of cfg, the secrets redactor knows of are redacted from the messages and the fields
+func New(cfg Config, redactor *Redactor) (*zap.SugaredLogger, error) {
+	level := zapcore.InfoLevel
+	if cfg.Level != "" {
+		var err error
+		level, err = zapcore.ParseLevel(cfg.Level)
+		if err != nil {
+			return nil, fmt.Errorf("something wrong happened while parsing the log level: %w", err)
+		}
+	}
+	zapConfig := zap.NewDevelopmentConfig()
+	if cfg.Encoding == EncodingJSON {
+		zapConfig = zap.NewProductionConfig()
+	}
+	zapConfig.Development = false
+	zapConfig.Level = zap.NewAtomicLevelAt(level)
+	// sampling wraps the redaction below instead so the sampler decides before entries are redacted and written
+	zapConfig.Sampling = nil
+	output := cfg.Output
+	if output == "" {
+		output = "stderr"
+	}
+	zapConfig.OutputPaths = []string{output}
+	zapConfig.ErrorOutputPaths = []string{"stderr"}
+	logger, err := zapConfig.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		core = &redactingCore{Core: core, redactor: redactor}
+		if cfg.Sampling.Initial > 0 {
+			core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.Sampling.Initial, cfg.Sampling.Thereafter)
+		}
+		return core
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while building logger: %w", err)
+	}
+	return logger.Sugar(), nil
+}
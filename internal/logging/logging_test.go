@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestNew_Redacts(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "bot.log")
+	logger, err := New(Config{Level: "debug", Encoding: EncodingJSON, Output: output}, NewRedactor("hunter2", "", "hunter2hunter2"))
+	if err != nil {
+		t.Fatalf("failed to build the logger: %v", err)
+	}
+	logger.Debugf("connecting with %s", "hunter2hunter2")
+	logger.Infow("failed to connect", "password", "hunter2", "error", errors.New("password hunter2 rejected"))
+	_ = logger.Sync()
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := string(b)
+	if strings.Contains(logs, "hunter2") || strings.Count(logs, Redacted) != 3 {
+		t.Fatalf("expected every secret to be redacted once, got:\n%s", logs)
+	}
+	if !strings.Contains(logs, `"password":"[REDACTED]"`) || !strings.Contains(logs, `"error":"password [REDACTED] rejected"`) {
+		t.Fatalf("expected the fields to be redacted in place, got:\n%s", logs)
+	}
+}
+
+func TestNew_RedactsStructuredFields(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "bot.log")
+	logger, err := New(Config{Level: "debug", Encoding: EncodingJSON, Output: output}, NewRedactor("hunter2"))
+	if err != nil {
+		t.Fatalf("failed to build the logger: %v", err)
+	}
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	logger.Infow("connecting",
+		"credentials", credentials{User: "max", Password: "hunter2"},
+		"headers", map[string][]string{"Authorization": {"Bearer hunter2"}},
+		"body", []byte("token=hunter2"),
+		zap.ByteString("query", []byte("token=hunter2")),
+		zap.Strings("tokens", []string{"hunter2", "expired"}),
+	)
+	_ = logger.Sync()
+	b, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := string(b)
+	if strings.Contains(logs, "hunter2") {
+		t.Fatalf("expected every secret to be redacted, got:\n%s", logs)
+	}
+	for _, expected := range []string{`"credentials":{"user":"max","password":"[REDACTED]"}`, `"query":"token=[REDACTED]"`,
+		`"body":"` + base64.StdEncoding.EncodeToString([]byte("token=[REDACTED]")) + `"`,
+		`"headers":{"Authorization":["Bearer [REDACTED]"]}`, `"tokens":["[REDACTED]","expired"]`} {
+		if !strings.Contains(logs, expected) {
+			t.Fatalf("expected %s to be logged, got:\n%s", expected, logs)
+		}
+	}
+}
+
+func TestRedactor_Add(t *testing.T) {
+	redactor := NewRedactor()
+	if redactor.Redact("token abc") != "token abc" {
+		t.Fatalf("expected a redactor without secrets to leave everything as is")
+	}
+	redactor.Add("abc", "")
+	redactor.Add("abcdef", "abc")
+	if redacted := redactor.Redact("tokens abc and abcdef"); redacted != "tokens [REDACTED] and [REDACTED]" {
+		t.Fatalf("expected the added secrets to be redacted, got %s", redacted)
+	}
+	var nilRedactor *Redactor
+	nilRedactor.Add("abc")
+	if nilRedactor.Redact("token abc") != "token abc" {
+		t.Fatalf("expected a nil redactor to leave everything as is")
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	err := Config{Level: "loud", Encoding: "xml", Sampling: SamplingConfig{Initial: -1}}.Validate()
+	for _, expected := range []string{`level "loud"`, `encoding "xml"`, "sampling.initial -1"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected the error to report %s, got %v", expected, err)
+		}
+	}
+	if err := (Config{}).Validate(); err != nil {
+		t.Fatalf("expected the empty config to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package logging
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces the secrets in logs and in the errors served over HTTP
+const Redacted = "[REDACTED]"
+
+// Redactor replaces the secrets it was created with and the ones added since, a nil Redactor leaves everything as is
+type Redactor struct {
+	mu       sync.RWMutex
+	secrets  []string
+	replacer *strings.Replacer
+}
+
+// NewRedactor redacts the secrets that are not empty
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	r.Add(secrets...)
+	return r
+}
+
+// Add redacts the secrets that are not empty from now on, like the tokens obtained while the bot runs
+func (r *Redactor) Add(secrets ...string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added := false
+	for _, secret := range secrets {
+		if secret != "" && !slices.Contains(r.secrets, secret) {
+			r.secrets = append(r.secrets, secret)
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+	// the replacer tries the secrets in order so a secret containing another has to come first
+	slices.SortFunc(r.secrets, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	pairs := make([]string, 0, 2*len(r.secrets))
+	for _, secret := range r.secrets {
+		pairs = append(pairs, secret, Redacted)
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+// redacts tells whether the redactor has any secret to redact
+func (r *Redactor) redacts() bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.replacer != nil
+}
+
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
+
+// redactingCore redacts the messages and the string and error fields of the entries before they are written
+type redactingCore struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redactFields(fields)), redactor: c.redactor}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.Redact(entry.Message)
+	return c.Core.Write(entry, c.redactFields(fields))
+}
+
+func (c *redactingCore) redactFields(fields []zapcore.Field) []zapcore.Field {
+	if !c.redactor.redacts() {
+		return fields
+	}
+	redacted := make([]zapcore.Field, len(fields))
+	for index, field := range fields {
+		switch field.Type {
+		case zapcore.StringType:
+			field.String = c.redactor.Redact(field.String)
+		case zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok {
+				field = zap.String(field.Key, c.redactor.Redact(err.Error()))
+			}
+		case zapcore.StringerType:
+			if stringer, ok := field.Interface.(interface{ String() string }); ok {
+				field = zap.String(field.Key, c.redactor.Redact(stringer.String()))
+			}
+		case zapcore.ByteStringType:
+			if b, ok := field.Interface.([]byte); ok {
+				field = zap.ByteString(field.Key, []byte(c.redactor.Redact(string(b))))
+			}
+		case zapcore.BinaryType:
+			// the bytes logged with zap.Any are written base64 encoded so they are redacted before encoding
+			if b, ok := field.Interface.([]byte); ok {
+				field = zap.Binary(field.Key, []byte(c.redactor.Redact(string(b))))
+			}
+		case zapcore.ReflectType, zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+			field = c.redactEncoded(field)
+		}
+		redacted[index] = field
+	}
+	return redacted
+}
+
+// redactEncoded redacts the JSON encoding of a structured field, like the maps and structs logged with zap.Any.
+// Secrets are matched in their JSON form so a secret with characters JSON escapes is left as is.
+func (c *redactingCore) redactEncoded(field zapcore.Field) zapcore.Field {
+	encoder := zapcore.NewMapObjectEncoder()
+	field.AddTo(encoder)
+	b, err := json.Marshal(encoder.Fields[field.Key])
+	if err != nil {
+		// the field is written as is, with the encoding error zap reports for it
+		return field
+	}
+	return zap.Reflect(field.Key, json.RawMessage(c.redactor.Redact(string(b))))
+}
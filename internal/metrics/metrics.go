@@ -0,0 +1,107 @@
+// Package metrics holds the Prometheus collectors of the bot, they are registered on a registry of their own which
+// Handler serves
+package metrics
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "listen2maxpayne"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	PostsAttempted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_attempted_total",
+		Help:      "Excerpts the publisher tried to post, a thread counts as a single post.",
+	}, []string{"destination"})
+	PostsSucceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_succeeded_total",
+		Help:      "Excerpts posted in full.",
+	}, []string{"destination"})
+	PostsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "posts_failed_total",
+		Help:      "Excerpts that failed to be posted, by the type of the error.",
+	}, []string{"destination", "error_type"})
+	TwitterRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "twitter_request_duration_seconds",
+		Help:      "Latency of the requests to the Twitter API, code is 0 when no response was received.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "code"})
+	TwitterRateLimitRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "twitter_rate_limit_remaining",
+		Help:      "Requests left in the current rate limit window of the endpoint, as last reported by the Twitter API.",
+	}, []string{"endpoint"})
+	SelectionRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "excerpt_selection_retries_total",
+		Help:      "Random excerpts picked again, by reason, like posted_last when the one posted last was picked.",
+	}, []string{"reason"})
+	ImportRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "import_records_total",
+		Help:      "Excerpts processed by imports and corpus syncs, by whether they were saved or rejected.",
+	}, []string{"outcome"})
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of the database queries by statement, batches are observed as a whole.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"statement", "outcome"})
+)
+
+// nextPost returns when the next post is scheduled and whether one is, it is set once publishing starts
+var nextPost atomic.Pointer[func() (time.Time, bool)]
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		PostsAttempted,
+		PostsSucceeded,
+		PostsFailed,
+		TwitterRequestDuration,
+		TwitterRateLimitRemaining,
+		SelectionRetries,
+		ImportRecords,
+		DBQueryDuration,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "next_post_seconds",
+			Help:      "Seconds until the next scheduled post, -1 when publishing is paused or not started.",
+		}, secondsUntilNextPost),
+	)
+}
+
+// ObserveNextPost sets where the next post gauge reads the schedule from
+func ObserveNextPost(f func() (time.Time, bool)) {
+	nextPost.Store(&f)
+}
+
+func secondsUntilNextPost() float64 {
+	f := nextPost.Load()
+	if f == nil {
+		return -1
+	}
+	next, ok := (*f)()
+	if !ok {
+		return -1
+	}
+	return max(time.Until(next).Seconds(), 0)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
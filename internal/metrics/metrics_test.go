@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler(t *testing.T) {
+	PostsSucceeded.WithLabelValues("dry-run").Inc()
+	ObserveNextPost(func() (time.Time, bool) { return time.Now().Add(time.Hour), true })
+	t.Cleanup(func() { nextPost.Store(nil) })
+
+	res := httptest.NewRecorder()
+	Handler().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.Code)
+	}
+	body, _ := io.ReadAll(res.Body)
+	for _, expected := range []string{
+		`listen2maxpayne_posts_succeeded_total{destination="dry-run"} 1`,
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Fatalf("expected the metrics to contain %s, got:\n%s", expected, body)
+		}
+	}
+	// the gauge is read while the handler serves so only a range is known
+	match := regexp.MustCompile(`(?m)^listen2maxpayne_next_post_seconds (\S+)$`).FindSubmatch(body)
+	if match == nil {
+		t.Fatalf("expected the metrics to contain the next post gauge, got:\n%s", body)
+	}
+	seconds, err := strconv.ParseFloat(string(match[1]), 64)
+	if err != nil || seconds <= 3500 || seconds > 3600 {
+		t.Fatalf("expected the next post in about an hour, got %s: %v", match[1], err)
+	}
+}
+
+func TestSecondsUntilNextPost(t *testing.T) {
+	nextPost.Store(nil)
+	if seconds := secondsUntilNextPost(); seconds != -1 {
+		t.Fatalf("expected -1 before publishing starts, got %v", seconds)
+	}
+	ObserveNextPost(func() (time.Time, bool) { return time.Time{}, false })
+	t.Cleanup(func() { nextPost.Store(nil) })
+	if seconds := secondsUntilNextPost(); seconds != -1 {
+		t.Fatalf("expected -1 while paused, got %v", seconds)
+	}
+}
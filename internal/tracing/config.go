@@ -0,0 +1,39 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+type Config struct {
+	// Exporter is where the spans are sent, otlp or stdout, tracing is disabled when it is empty. The stdout exporter
+	// writes to stderr so spans do not end up in the output of the commands.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the host and port of the OTLP/HTTP collector, like localhost:4318, the OTEL_EXPORTER_OTLP_*
+	// environment variables apply when it is empty
+	Endpoint string `yaml:"endpoint"`
+	// Insecure exports to the collector over plain HTTP
+	Insecure bool `yaml:"insecure"`
+	// SampleRatio is the share of the traces started by the bot that are sampled, all of them are when it is zero,
+	// the sampling decision of the parent span is kept
+	SampleRatio float64 `yaml:"sampleRatio"`
+}
+
+// Validate reports every invalid field at once, the whole section is optional
+func (c Config) Validate() error {
+	var errs []error
+	switch c.Exporter {
+	case "", ExporterOTLP, ExporterStdout:
+	default:
+		errs = append(errs, fmt.Errorf("exporter %q is neither %s nor %s", c.Exporter, ExporterOTLP, ExporterStdout))
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("sampleRatio %v must be between 0 and 1", c.SampleRatio))
+	}
+	return errors.Join(errs...)
+}
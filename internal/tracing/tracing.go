@@ -0,0 +1,72 @@
+// Package tracing installs the OpenTelemetry tracer provider the rest of the bot starts its spans from through
+// otel.Tracer, spans are dropped until Setup installs one
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const serviceName = "listen2maxpayne"
+
+// stdoutOutput is where the stdout exporter writes spans, stderr so they never get mixed in the output of the commands
+var stdoutOutput io.Writer = os.Stderr
+
+// ShutdownFunc exports the spans left and stops the exporter, giving up when ctx is done
+type ShutdownFunc func(ctx context.Context) error
+
+// Setup installs the global tracer provider exporting to the configured exporter, nothing is installed when
+// tracing is disabled
+func Setup(ctx context.Context, cfg Config) (ShutdownFunc, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Exporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while creating the %s exporter: %w", cfg.Exporter, err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("something wrong happened while describing the service: %w", err)
+	}
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdktrace.SpanExporter, error) {
+	if cfg.Exporter == ExporterStdout {
+		return stdouttrace.New(stdouttrace.WithPrettyPrint(), stdouttrace.WithWriter(stdoutOutput))
+	}
+	var options []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	return otlptracehttp.New(ctx, options...)
+}
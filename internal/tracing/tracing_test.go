@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// restoreGlobals puts back the tracer provider and propagator Setup replaces once the test is over
+func restoreGlobals(t *testing.T) {
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	restoreGlobals(t)
+	provider := otel.GetTracerProvider()
+	shutdown, err := Setup(context.Background(), Config{})
+	if err != nil {
+		t.Fatalf("failed to set up disabled tracing: %v", err)
+	}
+	if otel.GetTracerProvider() != provider {
+		t.Fatalf("expected no tracer provider to be installed when tracing is disabled")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("expected the shutdown of disabled tracing to do nothing, got %v", err)
+	}
+	_, err = Setup(context.Background(), Config{Exporter: "jaeger"})
+	if err == nil {
+		t.Fatalf("expected an unknown exporter to be refused")
+	}
+}
+
+func TestSetup_Stdout(t *testing.T) {
+	restoreGlobals(t)
+	var output bytes.Buffer
+	stdoutOutput = &output
+	t.Cleanup(func() { stdoutOutput = os.Stderr })
+	shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout})
+	if err != nil {
+		t.Fatalf("failed to set up tracing: %v", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "schedule tick")
+	span.End()
+	err = shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("failed to shut down tracing: %v", err)
+	}
+	if !strings.Contains(output.String(), `"Name": "schedule tick"`) || !strings.Contains(output.String(), serviceName) {
+		t.Fatalf("expected the span to be exported, got %s", output.String())
+	}
+}
+
+func TestSetup_Sampler(t *testing.T) {
+	restoreGlobals(t)
+	t.Cleanup(func() { stdoutOutput = os.Stderr })
+	tests := []struct {
+		ratio   float64
+		sampled bool
+	}{
+		{ratio: 0, sampled: true},
+		{ratio: 1e-12, sampled: false},
+	}
+	for _, tt := range tests {
+		stdoutOutput = &bytes.Buffer{}
+		shutdown, err := Setup(context.Background(), Config{Exporter: ExporterStdout, SampleRatio: tt.ratio})
+		if err != nil {
+			t.Fatalf("failed to set up tracing: %v", err)
+		}
+		_, span := otel.Tracer("test").Start(context.Background(), "schedule tick")
+		if span.SpanContext().IsSampled() != tt.sampled {
+			t.Fatalf("expected the span to be sampled %v with a ratio of %v", tt.sampled, tt.ratio)
+		}
+		span.End()
+		// the decision of a sampled parent is kept whatever the ratio
+		parent := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1}, SpanID: trace.SpanID{1}, TraceFlags: trace.FlagsSampled,
+		}))
+		_, child := otel.Tracer("test").Start(parent, "publish excerpt")
+		if !child.SpanContext().IsSampled() {
+			t.Fatalf("expected the child of a sampled parent to be sampled with a ratio of %v", tt.ratio)
+		}
+		child.End()
+		_ = shutdown(context.Background())
+	}
+}
@@ -7,47 +7,78 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
+	"github.com/aaegamysta/listen-2-max-payne/internal/logging"
 	"github.com/dghubble/oauth1"
 	"go.uber.org/zap"
 )
 
 type Interface interface {
 	Post(ctx context.Context, tweet Tweet) (SucessfullTweetResponse, error)
+	Delete(ctx context.Context, tweetID string) error
+	// VerifyCredentials returns the user the client posts as, it is a lightweight authenticated call to check that
+	// the credentials are still accepted
+	VerifyCredentials(ctx context.Context) (User, error)
 }
 
 type Impl struct {
 	logger     *zap.SugaredLogger
 	httpClient *http.Client
 	endpoint   string
+	// usersMeURL is joined to the API base of the endpoint so both point at the same API
+	usersMeURL string
 }
 
-func New(ctx context.Context, cfg Config, logger *zap.SugaredLogger) Interface {
-	oauth1Config := oauth1.NewConfig(cfg.ConsumerKey, cfg.ConsumerSecret)
-	token := oauth1.NewToken(cfg.AccessToken, cfg.AccessSecret)
-	// What are the repercussion of having context.Background backed into the oauth1ConfiguredClient
-	// and it doesn't change or rather not creating a
-	// oauth1ConfiguredClient with every call
-	oauth1ConfiguredClient := oauth1Config.Client(ctx, token)
+// New creates a client authenticated with oauth1 static keys or, when cfg.Auth is oauth2, with the user context
+// token kept in store which is refreshed automatically before it expires, its access and refresh tokens are added
+// to redactor
+func New(
+	ctx context.Context,
+	cfg Config,
+	logger *zap.SugaredLogger,
+	redactor *logging.Redactor,
+	store TokenStore,
+) Interface {
+	var httpClient *http.Client
+	if cfg.Auth == AuthOAuth2 {
+		httpClient = newOAuth2Client(ctx, cfg.OAuth2, logger, redactor, store)
+	} else {
+		oauth1Config := oauth1.NewConfig(cfg.ConsumerKey, cfg.ConsumerSecret)
+		token := oauth1.NewToken(cfg.AccessToken, cfg.AccessSecret)
+		// What are the repercussion of having context.Background backed into the oauth1ConfiguredClient
+		// and it doesn't change or rather not creating a
+		// oauth1ConfiguredClient with every call
+		httpClient = oauth1Config.Client(ctx, token)
+	}
 	return &Impl{
 		logger:     logger,
-		httpClient: oauth1ConfiguredClient,
+		httpClient: instrument(httpClient, cfg.Endpoint),
 		endpoint:   cfg.Endpoint,
+		usersMeURL: usersMeURL(cfg.Endpoint),
 	}
 }
 
-func (i *Impl) Post(ctx context.Context, e Tweet) (SucessfullTweetResponse, error) {
-	tweet := map[string]interface{}{
-		"text": e.Text,
+// usersMeURL joins users/me to the API base the tweets endpoint is under, like https://api.twitter.com/2/users/me
+// for https://api.twitter.com/2/tweets, an endpoint not ending with tweets is taken as the API base itself
+func usersMeURL(endpoint string) string {
+	base := strings.TrimSuffix(strings.TrimSuffix(endpoint, "/"), "/tweets")
+	u, err := url.JoinPath(base, "users", "me")
+	if err != nil {
+		return endpoint
 	}
-	jsonData, err := json.Marshal(tweet)
+	return u
+}
+
+func (i *Impl) Post(ctx context.Context, e Tweet) (SucessfullTweetResponse, error) {
+	jsonData, err := json.Marshal(e)
 	if err != nil {
-		i.logger.Panicf("failed to marshal tweet: %v", err)
+		return SucessfullTweetResponse{}, fmt.Errorf("something happened while marshalling the tweet: %w", err)
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.endpoint, strings.NewReader(string(jsonData)))
 	if err != nil {
-		i.logger.Panicf("failed to create request %v", err)
+		return SucessfullTweetResponse{}, fmt.Errorf("something happened while creating the request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	res, err := i.httpClient.Do(req)
@@ -57,17 +88,17 @@ func (i *Impl) Post(ctx context.Context, e Tweet) (SucessfullTweetResponse, erro
 	if res.Body == nil {
 		return SucessfullTweetResponse{}, errors.New("something happened while posting doing request: the response body was empty")
 	}
+	defer res.Body.Close()
 	if res.StatusCode != http.StatusCreated {
 		var tweetError TweetError
 		b, _ := io.ReadAll(res.Body)
 		err = json.Unmarshal(b, &tweetError)
-		if err != nil {
+		if err == nil {
 			return SucessfullTweetResponse{}, tweetError
 		}
 		return SucessfullTweetResponse{},
 			fmt.Errorf("failed to unmarshall the tweet error response %w here is the stringified response: %s", err, b)
 	}
-	defer res.Body.Close()
 	var successfulTweetRes SucessfullTweetResponse
 	b, err := io.ReadAll(res.Body)
 	if err != nil {
@@ -82,3 +113,66 @@ func (i *Impl) Post(ctx context.Context, e Tweet) (SucessfullTweetResponse, erro
 	}
 	return successfulTweetRes, nil
 }
+
+func (i *Impl) Delete(ctx context.Context, tweetID string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, i.endpoint+"/"+url.PathEscape(tweetID), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %w", err)
+	}
+	res, err := i.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("something happened while deleting tweet %s: %w", tweetID, err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the delete tweet response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var tweetError TweetError
+		err = json.Unmarshal(b, &tweetError)
+		if err == nil {
+			return tweetError
+		}
+		return fmt.Errorf("failed to unmarshall the tweet error response %w here is the stringified response: %s", err, b)
+	}
+	var deleteRes DeletedTweetResponse
+	err = json.Unmarshal(b, &deleteRes)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshall the delete tweet response: %w the body looks like so %s", err, b)
+	}
+	if !deleteRes.Data.Deleted {
+		return fmt.Errorf("twitter did not delete tweet %s", tweetID)
+	}
+	return nil
+}
+
+func (i *Impl) VerifyCredentials(ctx context.Context) (User, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, i.usersMeURL, nil)
+	if err != nil {
+		return User{}, fmt.Errorf("failed to create users/me request: %w", err)
+	}
+	res, err := i.httpClient.Do(req)
+	if err != nil {
+		return User{}, fmt.Errorf("something happened while verifying the credentials: %w", err)
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return User{}, fmt.Errorf("failed to read the users/me response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var tweetError TweetError
+		err = json.Unmarshal(b, &tweetError)
+		if err == nil {
+			return User{}, tweetError
+		}
+		return User{}, fmt.Errorf("failed to unmarshall the users/me error response %w here is the stringified response: %s", err, b)
+	}
+	var userRes UserResponse
+	err = json.Unmarshal(b, &userRes)
+	if err != nil {
+		return User{}, fmt.Errorf("failed to unmarshall the users/me response: %w the body looks like so %s", err, b)
+	}
+	return userRes.Data, nil
+}
@@ -0,0 +1,132 @@
+package twitter_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter/twittertest"
+	"go.uber.org/zap"
+)
+
+func TestImpl_Post(t *testing.T) {
+	server := twittertest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	client := twitter.New(ctx, server.Config(), zap.NewNop().Sugar(), nil, nil)
+
+	res, err := client.Post(ctx, twitter.Tweet{Text: "They were all dead."})
+	if err != nil {
+		t.Fatalf("failed to post tweet: %v", err)
+	}
+	if res.Data.ID == "" || res.Data.Text != "They were all dead." {
+		t.Fatalf("unexpected response %+v", res)
+	}
+	reply, err := client.Post(ctx, twitter.Tweet{
+		Text:  "The final gunshot was an exclamation mark.",
+		Reply: &twitter.TweetReply{InReplyToTweetID: res.Data.ID},
+	})
+	if err != nil {
+		t.Fatalf("failed to post reply: %v", err)
+	}
+	posted := server.Tweets()
+	if len(posted) != 2 || posted[1].ID != reply.Data.ID || posted[1].Tweet.Reply.InReplyToTweetID != res.Data.ID {
+		t.Fatalf("unexpected posted tweets %+v", posted)
+	}
+}
+
+func TestImpl_PostErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		inject []twitter.TweetError
+		status int
+	}{
+		{name: "unauthorized", inject: []twitter.TweetError{twittertest.ErrUnauthorized}, status: http.StatusUnauthorized},
+		{name: "duplicate", inject: []twitter.TweetError{twittertest.ErrDuplicate}, status: http.StatusForbidden},
+		{name: "rate limited", inject: []twitter.TweetError{twittertest.ErrTooManyRequests}, status: http.StatusTooManyRequests},
+		{name: "unavailable", inject: []twitter.TweetError{twittertest.ErrServiceUnavailable}, status: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := twittertest.NewServer()
+			defer server.Close()
+			ctx := context.Background()
+			client := twitter.New(ctx, server.Config(), zap.NewNop().Sugar(), nil, nil)
+			server.FailNext(tt.inject...)
+			_, err := client.Post(ctx, twitter.Tweet{Text: "Hell's Kitchen."})
+			var tweetError twitter.TweetError
+			if !errors.As(err, &tweetError) || tweetError.Status != tt.status {
+				t.Fatalf("expected a tweet error with status %d, got %v", tt.status, err)
+			}
+			if len(server.Tweets()) != 0 {
+				t.Fatalf("expected nothing to be posted")
+			}
+		})
+	}
+}
+
+func TestImpl_PostInvalidCredentials(t *testing.T) {
+	server := twittertest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	cfg := server.Config()
+	cfg.AccessSecret = "wrong-secret"
+	client := twitter.New(ctx, cfg, zap.NewNop().Sugar(), nil, nil)
+	_, err := client.Post(ctx, twitter.Tweet{Text: "Hell's Kitchen."})
+	var tweetError twitter.TweetError
+	if !errors.As(err, &tweetError) || tweetError.Status != http.StatusUnauthorized {
+		t.Fatalf("expected the signature to be rejected, got %v", err)
+	}
+}
+
+func TestImpl_PostInvalidEndpoint(t *testing.T) {
+	ctx := context.Background()
+	cfg := twitter.Config{Endpoint: "://not a url", ConsumerKey: "key", ConsumerSecret: "secret"}
+	client := twitter.New(ctx, cfg, zap.NewNop().Sugar(), nil, nil)
+	_, err := client.Post(ctx, twitter.Tweet{Text: "Hell's Kitchen."})
+	if err == nil {
+		t.Fatal("expected the request not to be created")
+	}
+}
+
+func TestImpl_PostRateLimit(t *testing.T) {
+	server := twittertest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	client := twitter.New(ctx, server.Config(), zap.NewNop().Sugar(), nil, nil)
+	server.SetRateLimit(1)
+	_, err := client.Post(ctx, twitter.Tweet{Text: "Roscoe Street Station."})
+	if err != nil {
+		t.Fatalf("failed to post tweet within the rate limit: %v", err)
+	}
+	_, err = client.Post(ctx, twitter.Tweet{Text: "Live from the crime scene."})
+	var tweetError twitter.TweetError
+	if !errors.As(err, &tweetError) || tweetError.Status != http.StatusTooManyRequests {
+		t.Fatalf("expected the rate limit to be exhausted, got %v", err)
+	}
+}
+
+func TestImpl_VerifyCredentials(t *testing.T) {
+	server := twittertest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+	client := twitter.New(ctx, server.Config(), zap.NewNop().Sugar(), nil, nil)
+	user, err := client.VerifyCredentials(ctx)
+	if err != nil || user != twittertest.User {
+		t.Fatalf("expected the credentials to be verified as %+v, got %+v and %v", twittertest.User, user, err)
+	}
+	server.FailNext(twittertest.ErrUnauthorized)
+	_, err = client.VerifyCredentials(ctx)
+	var tweetError twitter.TweetError
+	if !errors.As(err, &tweetError) || tweetError.Status != http.StatusUnauthorized {
+		t.Fatalf("expected the credentials to be rejected, got %v", err)
+	}
+	cfg := server.Config()
+	cfg.Endpoint += "/"
+	_, err = twitter.New(ctx, cfg, zap.NewNop().Sugar(), nil, nil).VerifyCredentials(ctx)
+	if err != nil {
+		t.Fatalf("expected users/me to be found next to an endpoint with a trailing slash, got %v", err)
+	}
+}
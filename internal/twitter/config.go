@@ -1,11 +1,64 @@
 package twitter
 
+import (
+	"errors"
+	"fmt"
+)
+
 const MaxTweetLength = 280
 
+const (
+	AuthOAuth1 = "oauth1"
+	AuthOAuth2 = "oauth2"
+)
+
 type Config struct {
-	ConsumerKey    string `yaml:"consumerKey"`
-	ConsumerSecret string `yaml:"consumerSecret"`
-	AccessToken    string `yaml:"accessToken"`
-	AccessSecret   string `yaml:"accessSecret"`
-	Endpoint       string `yaml:"endpoint"`
+	// Auth is either oauth1, the default, which uses the static consumer and access keys or oauth2 which uses the
+	// user context token obtained with the auth command
+	Auth           string       `yaml:"auth"`
+	ConsumerKey    string       `yaml:"consumerKey"`
+	ConsumerSecret string       `yaml:"consumerSecret" secret:"true"`
+	AccessToken    string       `yaml:"accessToken" secret:"true"`
+	AccessSecret   string       `yaml:"accessSecret" secret:"true"`
+	OAuth2         OAuth2Config `yaml:"oauth2"`
+	Endpoint       string       `yaml:"endpoint"`
+}
+
+type OAuth2Config struct {
+	ClientID string `yaml:"clientID"`
+	// ClientSecret is only needed for confidential clients, public clients rely on PKCE alone
+	ClientSecret string `yaml:"clientSecret" secret:"true"`
+	// RedirectURL has to be registered in the developer portal, the auth command listens on its host and path
+	RedirectURL string   `yaml:"redirectURL"`
+	Scopes      []string `yaml:"scopes"`
+	AuthURL     string   `yaml:"authURL"`
+	TokenURL    string   `yaml:"tokenURL"`
+}
+
+// Validate reports every missing or invalid field at once, the credentials required depend on Auth
+func (c Config) Validate() error {
+	var errs []error
+	switch c.Auth {
+	case "", AuthOAuth1:
+		for _, key := range []struct{ name, value string }{
+			{"consumerKey", c.ConsumerKey},
+			{"consumerSecret", c.ConsumerSecret},
+			{"accessToken", c.AccessToken},
+			{"accessSecret", c.AccessSecret},
+		} {
+			if key.value == "" {
+				errs = append(errs, fmt.Errorf("%s is required with oauth1", key.name))
+			}
+		}
+	case AuthOAuth2:
+		if c.OAuth2.ClientID == "" {
+			errs = append(errs, errors.New("oauth2.clientID is required with oauth2"))
+		}
+		if c.OAuth2.RedirectURL == "" {
+			errs = append(errs, errors.New("oauth2.redirectURL is required with oauth2"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("auth %q is neither %s nor %s", c.Auth, AuthOAuth1, AuthOAuth2))
+	}
+	return errors.Join(errs...)
 }
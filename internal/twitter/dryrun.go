@@ -0,0 +1,116 @@
+package twitter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DryRunRecorder keeps the history of tweets that would have been posted apart from the real posting history
+type DryRunRecorder interface {
+	InsertDryRunTweet(ctx context.Context, tweet Tweet, res SucessfullTweetResponse) error
+}
+
+// DryRun is a destination that renders what would have been posted instead of calling the Twitter API
+type DryRun struct {
+	logger   *zap.SugaredLogger
+	recorder DryRunRecorder
+	mu       sync.Mutex
+	out      io.Writer
+}
+
+// NewDryRun renders to the logger when output is empty, otherwise it appends to the file at output
+func NewDryRun(_ context.Context, output string, logger *zap.SugaredLogger, recorder DryRunRecorder) (Interface, error) {
+	d := &DryRun{
+		logger:   logger,
+		recorder: recorder,
+	}
+	if output != "" {
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("something wrong happened while opening dry run output file: %w", err)
+		}
+		d.out = f
+	}
+	return d, nil
+}
+
+func (d *DryRun) Post(ctx context.Context, tweet Tweet) (SucessfullTweetResponse, error) {
+	res := SucessfullTweetResponse{
+		Data: TweetData{
+			ID:   fmt.Sprintf("dry-run-%d", time.Now().UnixNano()),
+			Text: tweet.Text,
+		},
+	}
+	res.Data.EditHistoryTweetIDs = []string{res.Data.ID}
+	rendered := renderDryRun(tweet, res)
+	if d.out == nil {
+		d.logger.Infow("dry run, would have posted", "destination", "dry-run", "tweet_id", res.Data.ID, "tweet", rendered)
+	} else {
+		d.mu.Lock()
+		_, err := io.WriteString(d.out, rendered)
+		d.mu.Unlock()
+		if err != nil {
+			return SucessfullTweetResponse{}, fmt.Errorf("something wrong happened while writing dry run tweet: %w", err)
+		}
+	}
+	err := d.recorder.InsertDryRunTweet(ctx, tweet, res)
+	if err != nil {
+		return SucessfullTweetResponse{}, fmt.Errorf("failed to record dry run tweet: %w", err)
+	}
+	return res, nil
+}
+
+func (d *DryRun) Delete(_ context.Context, tweetID string) error {
+	rendered := fmt.Sprintf("--- deleted %s at %s ---\n", tweetID, time.Now().Format(time.RFC3339))
+	if d.out == nil {
+		d.logger.Infow("dry run, would have deleted tweet", "destination", "dry-run", "tweet_id", tweetID)
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err := io.WriteString(d.out, rendered)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while writing dry run deletion: %w", err)
+	}
+	return nil
+}
+
+// Close closes the output file, nothing can be posted afterwards
+func (d *DryRun) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	closer, ok := d.out.(io.Closer)
+	if !ok {
+		return nil
+	}
+	err := closer.Close()
+	if err != nil {
+		return fmt.Errorf("something wrong happened while closing dry run output file: %w", err)
+	}
+	return nil
+}
+
+// VerifyCredentials succeeds without calling Twitter since nothing is posted in dry run mode
+func (d *DryRun) VerifyCredentials(context.Context) (User, error) {
+	return User{ID: "dry-run", Name: "dry run", Username: "dry-run"}, nil
+}
+
+func renderDryRun(tweet Tweet, res SucessfullTweetResponse) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s at %s", res.Data.ID, time.Now().Format(time.RFC3339))
+	if tweet.Reply != nil {
+		fmt.Fprintf(&b, " in reply to %s", tweet.Reply.InReplyToTweetID)
+	}
+	fmt.Fprintf(&b, " (%d/%d characters) ---\n%s\n", len([]rune(tweet.Text)), MaxTweetLength, tweet.Text)
+	if tweet.Media != nil && len(tweet.Media.MediaIDs) > 0 {
+		fmt.Fprintf(&b, "media: %s\n", strings.Join(tweet.Media.MediaIDs, ", "))
+	}
+	return b.String()
+}
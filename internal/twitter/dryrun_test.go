@@ -0,0 +1,46 @@
+package twitter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+type fakeRecorder struct {
+	tweets []Tweet
+}
+
+func (f *fakeRecorder) InsertDryRunTweet(_ context.Context, tweet Tweet, _ SucessfullTweetResponse) error {
+	f.tweets = append(f.tweets, tweet)
+	return nil
+}
+
+func TestDryRun_PostToFile(t *testing.T) {
+	ctx := context.Background()
+	output := filepath.Join(t.TempDir(), "tweets.log")
+	recorder := &fakeRecorder{}
+	client, err := NewDryRun(ctx, output, zap.NewNop().Sugar(), recorder)
+	if err != nil {
+		t.Fatalf("failed to create the dry run destination: %v", err)
+	}
+	_, err = client.Post(ctx, Tweet{Text: "They were all dead."})
+	if err != nil || len(recorder.tweets) != 1 {
+		t.Fatalf("expected the tweet to be recorded, got %v", err)
+	}
+	err = client.(*DryRun).Close()
+	if err != nil {
+		t.Fatalf("failed to close the dry run destination: %v", err)
+	}
+	rendered, err := os.ReadFile(output)
+	if err != nil || !strings.Contains(string(rendered), "They were all dead.") {
+		t.Fatalf("expected the tweet to be written out, got %q: %v", rendered, err)
+	}
+	_, err = client.Post(ctx, Tweet{Text: "The final gunshot."})
+	if err == nil {
+		t.Fatalf("expected posting to fail once the output file is closed")
+	}
+}
@@ -0,0 +1,64 @@
+package twitter
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// instrumentedTransport observes the latency of the requests to the API and the rate limit it reports
+type instrumentedTransport struct {
+	base http.RoundTripper
+	// tweetsPath is the path of the configured endpoint, the IDs appended to it are left out of the endpoint label
+	tweetsPath string
+}
+
+// instrument returns a copy of client observed by the metrics and traced, the trace context is propagated in the
+// headers which the oauth1 signature does not cover
+func instrument(client *http.Client, endpoint string) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	tweetsPath := ""
+	if u, err := url.Parse(endpoint); err == nil {
+		tweetsPath = strings.TrimSuffix(u.Path, "/")
+	}
+	transport := &instrumentedTransport{base: base, tweetsPath: tweetsPath}
+	instrumented := *client
+	instrumented.Transport = otelhttp.NewTransport(transport,
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return "twitter " + transport.endpointLabel(req)
+		}),
+	)
+	return &instrumented
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := t.endpointLabel(req)
+	start := time.Now()
+	res, err := t.base.RoundTrip(req)
+	code := "0"
+	if err == nil {
+		code = strconv.Itoa(res.StatusCode)
+		remaining, parseErr := strconv.Atoi(res.Header.Get("x-rate-limit-remaining"))
+		if parseErr == nil {
+			metrics.TwitterRateLimitRemaining.WithLabelValues(endpoint).Set(float64(remaining))
+		}
+	}
+	metrics.TwitterRequestDuration.WithLabelValues(endpoint, code).Observe(time.Since(start).Seconds())
+	return res, err
+}
+
+func (t *instrumentedTransport) endpointLabel(req *http.Request) string {
+	path := req.URL.Path
+	if t.tweetsPath != "" && strings.HasPrefix(path, t.tweetsPath+"/") {
+		path = t.tweetsPath + "/:id"
+	}
+	return req.Method + " " + path
+}
@@ -0,0 +1,209 @@
+package twitter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/logging"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	defaultRedirectURL = "http://127.0.0.1:8765/callback"
+	defaultAuthURL     = "https://twitter.com/i/oauth2/authorize"
+	defaultTokenURL    = "https://api.twitter.com/2/oauth2/token"
+	// refreshMargin is how long before its expiry an access token gets refreshed
+	refreshMargin = 5 * time.Minute
+)
+
+var defaultScopes = []string{"tweet.read", "tweet.write", "users.read", "offline.access"}
+
+var ErrNoToken = errors.New("no oauth2 token is stored, run the auth command first")
+
+// TokenStore persists the OAuth 2.0 token, including the refresh token which is rotated on every refresh
+type TokenStore interface {
+	// LoadOAuth2Token returns ErrNoToken when nothing has been stored yet
+	LoadOAuth2Token(ctx context.Context) (*oauth2.Token, error)
+	SaveOAuth2Token(ctx context.Context, token *oauth2.Token) error
+}
+
+func (cfg OAuth2Config) oauth2Config() *oauth2.Config {
+	c := &oauth2.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  cfg.AuthURL,
+			TokenURL: cfg.TokenURL,
+		},
+	}
+	if c.RedirectURL == "" {
+		c.RedirectURL = defaultRedirectURL
+	}
+	if len(c.Scopes) == 0 {
+		c.Scopes = defaultScopes
+	}
+	if c.Endpoint.AuthURL == "" {
+		c.Endpoint.AuthURL = defaultAuthURL
+	}
+	if c.Endpoint.TokenURL == "" {
+		c.Endpoint.TokenURL = defaultTokenURL
+	}
+	return c
+}
+
+// persistingTokenSource lazily loads the token from the store and refreshes it ahead of its expiry,
+// saving the rotated refresh token back to the store. Every token it holds is added to the redactor.
+type persistingTokenSource struct {
+	ctx      context.Context
+	logger   *zap.SugaredLogger
+	redactor *logging.Redactor
+	cfg      *oauth2.Config
+	store    TokenStore
+	mu       sync.Mutex
+	token    *oauth2.Token
+}
+
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		token, err := s.store.LoadOAuth2Token(s.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load oauth2 token: %w", err)
+		}
+		s.redactor.Add(token.AccessToken, token.RefreshToken)
+		s.token = token
+	}
+	if s.token.Expiry.IsZero() || time.Until(s.token.Expiry) > refreshMargin {
+		return s.token, nil
+	}
+	// moving the expiry forward makes the oauth2 package consider the token expired and refresh it now
+	expiring := *s.token
+	expiring.Expiry = expiring.Expiry.Add(-refreshMargin)
+	refreshed, err := s.cfg.TokenSource(s.ctx, &expiring).Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh oauth2 token: %w", err)
+	}
+	s.redactor.Add(refreshed.AccessToken, refreshed.RefreshToken)
+	err = s.store.SaveOAuth2Token(s.ctx, refreshed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to persist refreshed oauth2 token: %w", err)
+	}
+	s.logger.Infow("refreshed oauth2 token", "expiry", refreshed.Expiry)
+	s.token = refreshed
+	return s.token, nil
+}
+
+func newOAuth2Client(
+	ctx context.Context,
+	cfg OAuth2Config,
+	logger *zap.SugaredLogger,
+	redactor *logging.Redactor,
+	store TokenStore,
+) *http.Client {
+	source := &persistingTokenSource{
+		// refreshing must keep working while the bot drains its last post after being asked to stop
+		ctx:      context.WithoutCancel(ctx),
+		logger:   logger,
+		redactor: redactor,
+		cfg:      cfg.oauth2Config(),
+		store:    store,
+	}
+	// unlike oauth2.NewClient, which caches the token until the oauth2 package considers it expired, every request
+	// asks source so the token is refreshed refreshMargin ahead of its expiry
+	return &http.Client{Transport: &oauth2.Transport{Source: source}}
+}
+
+// Authorize runs the OAuth 2.0 Authorization Code flow with PKCE. It writes the authorization URL to out,
+// waits for the callback on the redirect URL and stores the exchanged token.
+func Authorize(ctx context.Context, cfg OAuth2Config, logger *zap.SugaredLogger, store TokenStore, out io.Writer) error {
+	conf := cfg.oauth2Config()
+	redirectURL, err := url.Parse(conf.RedirectURL)
+	if err != nil {
+		return fmt.Errorf("invalid oauth2 redirect url: %w", err)
+	}
+	state, err := randomState()
+	if err != nil {
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	listener, err := net.Listen("tcp", redirectURL.Host)
+	if err != nil {
+		return fmt.Errorf("something wrong happened while listening for the oauth2 callback: %w", err)
+	}
+	results := make(chan error, 1)
+	// only the first callback is reported, repeated ones must not block the handler
+	report := func(err error) {
+		select {
+		case results <- err:
+		default:
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(redirectURL.Path, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "state mismatch", http.StatusBadRequest)
+			return
+		}
+		if authErr := query.Get("error"); authErr != "" {
+			http.Error(w, "authorization was denied", http.StatusForbidden)
+			report(fmt.Errorf("authorization was denied: %s", authErr))
+			return
+		}
+		token, err := conf.Exchange(r.Context(), query.Get("code"), oauth2.VerifierOption(verifier))
+		if err != nil {
+			http.Error(w, "failed to exchange the authorization code", http.StatusInternalServerError)
+			report(fmt.Errorf("failed to exchange the authorization code: %w", err))
+			return
+		}
+		err = store.SaveOAuth2Token(r.Context(), token)
+		if err != nil {
+			http.Error(w, "failed to store the token", http.StatusInternalServerError)
+			report(fmt.Errorf("failed to store the oauth2 token: %w", err))
+			return
+		}
+		_, _ = io.WriteString(w, "listen-2-max-payne is authorized, you can close this window.\n")
+		report(nil)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Close()
+
+	authURL := conf.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+	_, _ = fmt.Fprintf(out, "Open the following URL in a browser to authorize the bot:\n\n%s\n\n", authURL)
+	logger.Infow("waiting for the oauth2 callback", "redirect_url", conf.RedirectURL)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-results:
+		if err == nil {
+			logger.Info("oauth2 token obtained and stored")
+		}
+		return err
+	}
+}
+
+func randomState() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate oauth2 state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
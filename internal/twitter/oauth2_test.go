@@ -0,0 +1,326 @@
+package twitter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/logging"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+type fakeTokenStore struct {
+	mu    sync.Mutex
+	token *oauth2.Token
+	saved int
+}
+
+func (f *fakeTokenStore) LoadOAuth2Token(context.Context) (*oauth2.Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token == nil {
+		return nil, ErrNoToken
+	}
+	return f.token, nil
+}
+
+func (f *fakeTokenStore) SaveOAuth2Token(_ context.Context, token *oauth2.Token) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.token = token
+	f.saved++
+	return nil
+}
+
+func (f *fakeTokenStore) stored() (*oauth2.Token, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.token, f.saved
+}
+
+// tokenServer is a token endpoint granting the rotated token for the refresh token and the authorization code it
+// expects, verifier is set to the PKCE verifier of the last authorization code exchange
+type tokenServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests int
+	verifier string
+}
+
+func newTokenServer(t *testing.T, refreshToken, code string) *tokenServer {
+	s := &tokenServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		err := r.ParseForm()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch r.PostForm.Get("grant_type") {
+		case "refresh_token":
+			if r.PostForm.Get("refresh_token") != refreshToken {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+		case "authorization_code":
+			if r.PostForm.Get("code") != code {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			s.verifier = r.PostForm.Get("code_verifier")
+		default:
+			http.Error(w, `{"error":"unsupported_grant_type"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "rotated-access-token",
+			"refresh_token": "rotated-refresh-token",
+			"token_type":    "bearer",
+			"expires_in":    7200,
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *tokenServer) requested() (int, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests, s.verifier
+}
+
+func TestPersistingTokenSource_Token(t *testing.T) {
+	tests := []struct {
+		name    string
+		expiry  time.Duration
+		refresh bool
+	}{
+		{name: "valid", expiry: time.Hour, refresh: false},
+		{name: "about to expire", expiry: 2 * time.Minute, refresh: true},
+		{name: "expired", expiry: -time.Minute, refresh: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTokenServer(t, "refresh-token", "")
+			store := &fakeTokenStore{token: &oauth2.Token{
+				AccessToken:  "access-token",
+				RefreshToken: "refresh-token",
+				TokenType:    "bearer",
+				Expiry:       time.Now().Add(tt.expiry),
+			}}
+			redactor := logging.NewRedactor()
+			source := &persistingTokenSource{
+				ctx:      context.Background(),
+				logger:   zap.NewNop().Sugar(),
+				redactor: redactor,
+				cfg:      OAuth2Config{ClientID: "client", TokenURL: server.URL}.oauth2Config(),
+				store:    store,
+			}
+			for range 2 {
+				token, err := source.Token()
+				if err != nil {
+					t.Fatalf("failed to get token: %v", err)
+				}
+				if tt.refresh && token.AccessToken != "rotated-access-token" {
+					t.Fatalf("expected the token to be refreshed, got %s", token.AccessToken)
+				}
+				if !tt.refresh && token.AccessToken != "access-token" {
+					t.Fatalf("expected the stored token to be used, got %s", token.AccessToken)
+				}
+			}
+			// the refreshed token is valid for another two hours so the second call must not refresh it again
+			requests, _ := server.requested()
+			stored, saved := store.stored()
+			held := []string{"access-token", "refresh-token"}
+			if tt.refresh {
+				held = append(held, "rotated-access-token", "rotated-refresh-token")
+			}
+			for _, token := range held {
+				if redacted := redactor.Redact("token " + token); redacted != "token "+logging.Redacted {
+					t.Fatalf("expected %s to be redacted, got %s", token, redacted)
+				}
+			}
+			if !tt.refresh {
+				if requests != 0 || saved != 0 {
+					t.Fatalf("expected no refresh, got %d requests and %d saves", requests, saved)
+				}
+				return
+			}
+			if requests != 1 || saved != 1 {
+				t.Fatalf("expected a single refresh, got %d requests and %d saves", requests, saved)
+			}
+			if stored.AccessToken != "rotated-access-token" || stored.RefreshToken != "rotated-refresh-token" {
+				t.Fatalf("expected the rotated token to be persisted, got %+v", stored)
+			}
+		})
+	}
+}
+
+func TestPersistingTokenSource_NoToken(t *testing.T) {
+	source := &persistingTokenSource{
+		ctx:    context.Background(),
+		logger: zap.NewNop().Sugar(),
+		cfg:    OAuth2Config{ClientID: "client"}.oauth2Config(),
+		store:  &fakeTokenStore{},
+	}
+	_, err := source.Token()
+	if !errors.Is(err, ErrNoToken) {
+		t.Fatalf("expected %v, got %v", ErrNoToken, err)
+	}
+}
+
+func TestNewOAuth2Client(t *testing.T) {
+	server := newTokenServer(t, "refresh-token", "")
+	var mu sync.Mutex
+	var authorizations []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+	}))
+	defer api.Close()
+	store := &fakeTokenStore{token: &oauth2.Token{
+		AccessToken:  "access-token",
+		RefreshToken: "refresh-token",
+		TokenType:    "bearer",
+		Expiry:       time.Now().Add(time.Hour),
+	}}
+	// the client outlives the context it was created with, like while the bot drains its last post
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newOAuth2Client(ctx, OAuth2Config{ClientID: "client", TokenURL: server.URL}, zap.NewNop().Sugar(), nil, store)
+	cancel()
+	call := func() {
+		res, err := client.Get(api.URL)
+		if err != nil {
+			t.Fatalf("failed to call the api: %v", err)
+		}
+		_ = res.Body.Close()
+	}
+	call()
+	// the token held by the client now expires within refreshMargin but long after the expiry delta of the oauth2
+	// package, it is refreshed all the same
+	source := client.Transport.(*oauth2.Transport).Source.(*persistingTokenSource)
+	source.mu.Lock()
+	source.token.Expiry = time.Now().Add(refreshMargin - time.Minute)
+	source.mu.Unlock()
+	call()
+	call()
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []string{"Bearer access-token", "Bearer rotated-access-token", "Bearer rotated-access-token"}
+	if !slices.Equal(authorizations, expected) {
+		t.Fatalf("expected the token to be refreshed ahead of its expiry, got %v", authorizations)
+	}
+	if requests, _ := server.requested(); requests != 1 {
+		t.Fatalf("expected a single refresh, got %d", requests)
+	}
+	if stored, _ := store.stored(); stored.AccessToken != "rotated-access-token" {
+		t.Fatalf("expected the refreshed token to be persisted, got %+v", stored)
+	}
+}
+
+// urlWriter hands over what Authorize writes, which holds the authorization URL
+type urlWriter chan string
+
+func (w urlWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestAuthorize(t *testing.T) {
+	server := newTokenServer(t, "", "authorization-code")
+	// the callback listener needs a fixed address, borrow a free port from the system
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	redirectURL := "http://" + listener.Addr().String() + "/callback"
+	_ = listener.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cfg := OAuth2Config{ClientID: "client", RedirectURL: redirectURL, AuthURL: "https://twitter.test/authorize", TokenURL: server.URL}
+	store := &fakeTokenStore{}
+	out := make(urlWriter, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- Authorize(ctx, cfg, zap.NewNop().Sugar(), store, out)
+	}()
+	var authURL *url.URL
+	select {
+	case written := <-out:
+		var urls []string
+		for _, field := range strings.Fields(written) {
+			if strings.HasPrefix(field, "https://") {
+				urls = append(urls, field)
+			}
+		}
+		if len(urls) != 1 {
+			t.Fatalf("expected the authorization url to be written, got %q", written)
+		}
+		authURL, err = url.Parse(urls[0])
+		if err != nil {
+			t.Fatalf("invalid authorization url: %v", err)
+		}
+	case err := <-done:
+		t.Fatalf("authorization ended before writing the url: %v", err)
+	}
+	query := authURL.Query()
+	state, challenge := query.Get("state"), query.Get("code_challenge")
+	if state == "" || challenge == "" || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected a state and a S256 code challenge, got %s", authURL)
+	}
+
+	callback := func(state string) int {
+		res, err := http.Get(redirectURL + "?" + url.Values{"state": {state}, "code": {"authorization-code"}}.Encode())
+		if err != nil {
+			t.Fatalf("failed to call back: %v", err)
+		}
+		_ = res.Body.Close()
+		return res.StatusCode
+	}
+	// a forged callback is refused without ending the flow or exchanging its code
+	if status := callback("forged-state"); status != http.StatusBadRequest {
+		t.Fatalf("expected a state mismatch to be refused, got %d", status)
+	}
+	if requests, _ := server.requested(); requests != 0 {
+		t.Fatalf("expected no code exchange on a state mismatch, got %d", requests)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("expected authorization to keep waiting after a state mismatch, got %v", err)
+	default:
+	}
+
+	if status := callback(state); status != http.StatusOK {
+		t.Fatalf("expected the callback to succeed, got %d", status)
+	}
+	err = <-done
+	if err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	_, verifier := server.requested()
+	sum := sha256.Sum256([]byte(verifier))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+		t.Fatalf("expected the code verifier to match the challenge")
+	}
+	token, saved := store.stored()
+	if saved != 1 || token.AccessToken != "rotated-access-token" || token.RefreshToken != "rotated-refresh-token" {
+		t.Fatalf("expected the exchanged token to be stored, got %+v", token)
+	}
+}
@@ -1,5 +1,15 @@
 package twitter
 
 type Tweet struct {
-	Text string `json:"text"`
+	Text  string      `json:"text"`
+	Reply *TweetReply `json:"reply,omitempty"`
+	Media *TweetMedia `json:"media,omitempty"`
+}
+
+type TweetReply struct {
+	InReplyToTweetID string `json:"in_reply_to_tweet_id"`
+}
+
+type TweetMedia struct {
+	MediaIDs []string `json:"media_ids"`
 }
@@ -12,6 +12,22 @@ type TweetData struct {
 	EditHistoryTweetIDs []string `json:"edit_history_tweet_ids"`
 }
 
+type DeletedTweetResponse struct {
+	Data struct {
+		Deleted bool `json:"deleted"`
+	} `json:"data"`
+}
+
+type UserResponse struct {
+	Data User `json:"data"`
+}
+
+type User struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
 type TweetError struct {
 	Title  string `json:"title"`
 	Type   string `json:"type"`
@@ -0,0 +1,50 @@
+package twitter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitThread splits text on word boundaries into parts that each fit in a single tweet, line breaks are kept
+// within a part and a word longer than MaxTweetLength is cut wherever the limit falls
+func SplitThread(text string) []string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= MaxTweetLength {
+		return []string{text}
+	}
+	parts := make([]string, 0)
+	var current strings.Builder
+	currentLength := 0
+	flush := func() {
+		if currentLength > 0 {
+			parts = append(parts, current.String())
+			current.Reset()
+			currentLength = 0
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		separator := byte('\n')
+		for _, word := range strings.Fields(line) {
+			wordLength := utf8.RuneCountInString(word)
+			for wordLength > MaxTweetLength {
+				flush()
+				runes := []rune(word)
+				parts = append(parts, string(runes[:MaxTweetLength]))
+				word = string(runes[MaxTweetLength:])
+				wordLength -= MaxTweetLength
+			}
+			if currentLength > 0 && currentLength+1+wordLength > MaxTweetLength {
+				flush()
+			}
+			if currentLength > 0 {
+				current.WriteByte(separator)
+				currentLength++
+			}
+			current.WriteString(word)
+			currentLength += wordLength
+			separator = ' '
+		}
+	}
+	flush()
+	return parts
+}
@@ -0,0 +1,27 @@
+package twitter
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitThread(t *testing.T) {
+	short := "They were all dead."
+	if parts := SplitThread(short); len(parts) != 1 || parts[0] != short {
+		t.Fatalf("expected a short text to fit in one tweet, got %q", parts)
+	}
+	long := strings.Repeat("The past is a gun pointed at my head. ", 20)
+	parts := SplitThread(long)
+	if len(parts) < 2 {
+		t.Fatalf("expected a long text to be split in a thread, got %d parts", len(parts))
+	}
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) > MaxTweetLength {
+			t.Fatalf("part is longer than a tweet: %q", part)
+		}
+	}
+	if strings.Join(parts, " ") != strings.TrimSpace(long) {
+		t.Fatalf("joining the thread back does not give the original text")
+	}
+}
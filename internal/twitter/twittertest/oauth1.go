@@ -0,0 +1,98 @@
+package twittertest
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // HMAC-SHA1 is mandated by OAuth1
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// verifyOAuth1 checks the Authorization header of r carries a valid HMAC-SHA1 signature as described in RFC 5849.
+func verifyOAuth1(r *http.Request, consumerKey, consumerSecret, accessToken, accessSecret string) error {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "OAuth ") {
+		return errors.New("missing OAuth authorization header")
+	}
+	oauthParams := make(map[string]string)
+	for _, pair := range strings.Split(strings.TrimPrefix(header, "OAuth "), ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return fmt.Errorf("malformed OAuth parameter %q", pair)
+		}
+		key, err := url.QueryUnescape(key)
+		if err != nil {
+			return fmt.Errorf("malformed OAuth parameter key %q: %w", key, err)
+		}
+		value, err = url.QueryUnescape(strings.Trim(value, `"`))
+		if err != nil {
+			return fmt.Errorf("malformed OAuth parameter value for %q: %w", key, err)
+		}
+		oauthParams[key] = value
+	}
+	if oauthParams["oauth_consumer_key"] != consumerKey {
+		return errors.New("unknown consumer key")
+	}
+	if oauthParams["oauth_token"] != accessToken {
+		return errors.New("unknown access token")
+	}
+	if oauthParams["oauth_signature_method"] != "HMAC-SHA1" {
+		return fmt.Errorf("unsupported signature method %q", oauthParams["oauth_signature_method"])
+	}
+	signature := oauthParams["oauth_signature"]
+	delete(oauthParams, "oauth_signature")
+
+	params := make([]string, 0)
+	for key, value := range oauthParams {
+		params = append(params, percentEncode(key)+"="+percentEncode(value))
+	}
+	for key, values := range r.URL.Query() {
+		for _, value := range values {
+			params = append(params, percentEncode(key)+"="+percentEncode(value))
+		}
+	}
+	if r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		err := r.ParseForm()
+		if err != nil {
+			return fmt.Errorf("malformed form body: %w", err)
+		}
+		for key, values := range r.PostForm {
+			for _, value := range values {
+				params = append(params, percentEncode(key)+"="+percentEncode(value))
+			}
+		}
+	}
+	sort.Strings(params)
+	baseURL := fmt.Sprintf("http://%s%s", strings.ToLower(r.Host), r.URL.EscapedPath())
+	base := strings.Join([]string{
+		r.Method,
+		percentEncode(baseURL),
+		percentEncode(strings.Join(params, "&")),
+	}, "&")
+
+	mac := hmac.New(sha1.New, []byte(percentEncode(consumerSecret)+"&"+percentEncode(accessSecret)))
+	mac.Write([]byte(base))
+	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("invalid OAuth signature")
+	}
+	return nil
+}
+
+// percentEncode escapes everything but the unreserved characters of RFC 3986.
+func percentEncode(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,264 @@
+// Package twittertest provides an in-process fake of the Twitter API v2 for integration tests.
+package twittertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aaegamysta/listen-2-max-payne/internal/twitter"
+)
+
+const (
+	ConsumerKey    = "test-consumer-key"
+	ConsumerSecret = "test-consumer-secret"
+	AccessToken    = "test-access-token"
+	AccessSecret   = "test-access-secret"
+
+	defaultRateLimit = 300
+)
+
+// Canned errors that can be injected with FailNext, their bodies mirror the ones returned by the real API.
+var (
+	ErrUnauthorized = twitter.TweetError{
+		Title: "Unauthorized", Type: "about:blank", Detail: "Unauthorized", Status: http.StatusUnauthorized,
+	}
+	ErrDuplicate = twitter.TweetError{
+		Title: "Forbidden", Type: "about:blank", Status: http.StatusForbidden,
+		Detail: "You are not allowed to create a Tweet with duplicate content.",
+	}
+	ErrTooManyRequests = twitter.TweetError{
+		Title: "Too Many Requests", Type: "about:blank", Detail: "Too Many Requests", Status: http.StatusTooManyRequests,
+	}
+	ErrServiceUnavailable = twitter.TweetError{
+		Title: "Service Unavailable", Type: "about:blank", Detail: "Service Unavailable", Status: http.StatusServiceUnavailable,
+	}
+	errNotFound = twitter.TweetError{
+		Title: "Not Found Error", Type: "https://api.twitter.com/2/problems/resource-not-found",
+		Detail: "Could not find tweet.", Status: http.StatusNotFound,
+	}
+)
+
+// User is the user the credentials of the server belong to.
+var User = twitter.User{ID: "2244994945", Name: "Max Payne", Username: "maxpayne"}
+
+// PostedTweet is a tweet accepted by the server.
+type PostedTweet struct {
+	ID    string
+	Tweet twitter.Tweet
+}
+
+// Server emulates POST /2/tweets, DELETE /2/tweets/:id, POST /2/media/upload and GET /2/users/me, verifying OAuth1
+// signatures and sending rate limit headers.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	nextID    int64
+	tweets    map[string]PostedTweet
+	order     []string
+	deleted   []string
+	media     []string
+	failures  []twitter.TweetError
+	rateLimit int
+	remaining int
+	reset     time.Time
+}
+
+// NewServer starts a fake server, it has to be closed by the caller.
+func NewServer() *Server {
+	s := &Server{
+		nextID:    1000,
+		tweets:    make(map[string]PostedTweet),
+		rateLimit: defaultRateLimit,
+		remaining: defaultRateLimit,
+		reset:     time.Now().Add(15 * time.Minute),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /2/tweets", s.handleCreateTweet)
+	mux.HandleFunc("DELETE /2/tweets/{id}", s.handleDeleteTweet)
+	mux.HandleFunc("POST /2/media/upload", s.handleMediaUpload)
+	mux.HandleFunc("GET /2/users/me", s.handleUsersMe)
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// Config returns a client configuration pointing at the server with the credentials it accepts.
+func (s *Server) Config() twitter.Config {
+	return twitter.Config{
+		ConsumerKey:    ConsumerKey,
+		ConsumerSecret: ConsumerSecret,
+		AccessToken:    AccessToken,
+		AccessSecret:   AccessSecret,
+		Endpoint:       s.URL + "/2/tweets",
+	}
+}
+
+// FailNext makes the next requests fail with the given errors in order, one error per request.
+func (s *Server) FailNext(errs ...twitter.TweetError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, errs...)
+}
+
+// SetRateLimit resets the rate limit window to allow limit requests, once exhausted requests fail with 429.
+func (s *Server) SetRateLimit(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = limit
+	s.remaining = limit
+	s.reset = time.Now().Add(15 * time.Minute)
+}
+
+// Tweets returns the tweets that are currently posted, in posting order.
+func (s *Server) Tweets() []PostedTweet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tweets := make([]PostedTweet, 0, len(s.order))
+	for _, id := range s.order {
+		if t, ok := s.tweets[id]; ok {
+			tweets = append(tweets, t)
+		}
+	}
+	return tweets
+}
+
+// Deleted returns the IDs of the deleted tweets, in deletion order.
+func (s *Server) Deleted() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.deleted...)
+}
+
+// Media returns the IDs of the uploaded media.
+func (s *Server) Media() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.media...)
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := verifyOAuth1(r, ConsumerKey, ConsumerSecret, AccessToken, AccessSecret)
+		if err != nil {
+			unauthorized := ErrUnauthorized
+			unauthorized.Detail = err.Error()
+			writeError(w, unauthorized)
+			return
+		}
+		s.mu.Lock()
+		exhausted := s.remaining == 0
+		if !exhausted {
+			s.remaining--
+		}
+		w.Header().Set("X-Rate-Limit-Limit", strconv.Itoa(s.rateLimit))
+		w.Header().Set("X-Rate-Limit-Remaining", strconv.Itoa(s.remaining))
+		w.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(s.reset.Unix(), 10))
+		var injected *twitter.TweetError
+		if len(s.failures) > 0 {
+			injected = &s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		s.mu.Unlock()
+		switch {
+		case injected != nil:
+			writeError(w, *injected)
+		case exhausted:
+			writeError(w, ErrTooManyRequests)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (s *Server) handleCreateTweet(w http.ResponseWriter, r *http.Request) {
+	var tweet twitter.Tweet
+	err := json.NewDecoder(r.Body).Decode(&tweet)
+	if err != nil || tweet.Text == "" {
+		writeError(w, twitter.TweetError{
+			Title: "Invalid Request", Type: "https://api.twitter.com/2/problems/invalid-request",
+			Detail: "One or more parameters to your request was invalid.", Status: http.StatusBadRequest,
+		})
+		return
+	}
+	s.mu.Lock()
+	for _, posted := range s.tweets {
+		if posted.Tweet.Text == tweet.Text {
+			s.mu.Unlock()
+			writeError(w, ErrDuplicate)
+			return
+		}
+	}
+	if tweet.Reply != nil {
+		if _, ok := s.tweets[tweet.Reply.InReplyToTweetID]; !ok {
+			s.mu.Unlock()
+			writeError(w, errNotFound)
+			return
+		}
+	}
+	s.nextID++
+	id := strconv.FormatInt(s.nextID, 10)
+	s.tweets[id] = PostedTweet{ID: id, Tweet: tweet}
+	s.order = append(s.order, id)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusCreated, twitter.SucessfullTweetResponse{
+		Data: twitter.TweetData{ID: id, Text: tweet.Text, EditHistoryTweetIDs: []string{id}},
+	})
+}
+
+func (s *Server) handleDeleteTweet(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	s.mu.Lock()
+	_, ok := s.tweets[id]
+	if ok {
+		delete(s.tweets, id)
+		s.deleted = append(s.deleted, id)
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, errNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"data": map[string]bool{"deleted": true}})
+}
+
+func (s *Server) handleMediaUpload(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("media")
+	if err != nil {
+		writeError(w, twitter.TweetError{
+			Title: "Invalid Request", Type: "https://api.twitter.com/2/problems/invalid-request",
+			Detail: fmt.Sprintf("missing media: %v", err), Status: http.StatusBadRequest,
+		})
+		return
+	}
+	defer file.Close()
+	size, _ := io.Copy(io.Discard, file)
+	s.mu.Lock()
+	s.nextID++
+	id := strconv.FormatInt(s.nextID, 10)
+	s.media = append(s.media, id)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]any{"data": map[string]any{"id": id, "media_key": "3_" + id, "size": size}})
+}
+
+func (s *Server) handleUsersMe(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, twitter.UserResponse{Data: User})
+}
+
+func writeError(w http.ResponseWriter, e twitter.TweetError) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	writeJSON(w, e.Status, e)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}